@@ -0,0 +1,191 @@
+package stheader
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DictionaryBuilder builds a Dictionary with chained method calls.
+// Keys and values are validated when they are added, and the errors
+// are reported by Build.
+type DictionaryBuilder struct {
+	dict *dictionary
+	errs []error
+}
+
+// NewDictionaryBuilder creates a new DictionaryBuilder.
+func NewDictionaryBuilder() *DictionaryBuilder {
+	return &DictionaryBuilder{dict: &dictionary{}}
+}
+
+// AddItem adds an Item without parameters. value is either a BareItem
+// or a value which NewBareItem accepts.
+func (b *DictionaryBuilder) AddItem(key string, value interface{}) *DictionaryBuilder {
+	return b.AddItemWithParams(key, value, nil)
+}
+
+// AddItemWithParams adds an Item with parameters. value is either
+// a BareItem or a value which NewBareItem accepts.
+func (b *DictionaryBuilder) AddItemWithParams(key string, value interface{}, params Parameters) *DictionaryBuilder {
+	bi, err := builderBareItem(value)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("key %q: %w", key, err))
+		return b
+	}
+	if err := builderParameters(params); err != nil {
+		b.errs = append(b.errs, fmt.Errorf("key %q: %w", key, err))
+		return b
+	}
+	return b.add(key, &member{val: &item{bareItem: bi, params: params}})
+}
+
+// AddInnerList adds an InnerList.
+func (b *DictionaryBuilder) AddInnerList(key string, items []Item, params Parameters) *DictionaryBuilder {
+	if err := builderInnerList(items, params); err != nil {
+		b.errs = append(b.errs, fmt.Errorf("key %q: %w", key, err))
+		return b
+	}
+	return b.add(key, &member{val: &innerList{items: items, params: params}})
+}
+
+func (b *DictionaryBuilder) add(key string, m Member) *DictionaryBuilder {
+	if !ValidKey(key) {
+		b.errs = append(b.errs, fmt.Errorf("invalid key: %q", key))
+		return b
+	}
+	if b.dict.index(key) != -1 {
+		b.errs = append(b.errs, fmt.Errorf("duplicate key: %q", key))
+		return b
+	}
+	b.dict.Store(key, m)
+	return b
+}
+
+// Build returns the built Dictionary, or an error which includes
+// all errors occurred while adding entries.
+func (b *DictionaryBuilder) Build() (Dictionary, error) {
+	if len(b.errs) > 0 {
+		return nil, joinBuilderErrors(b.errs)
+	}
+	return b.dict, nil
+}
+
+// ListBuilder builds a List with chained method calls.
+// Values are validated when they are added, and the errors are
+// reported by Build.
+type ListBuilder struct {
+	list List
+	errs []error
+}
+
+// NewListBuilder creates a new ListBuilder.
+func NewListBuilder() *ListBuilder {
+	return &ListBuilder{list: List{}}
+}
+
+// AddItem adds an Item without parameters. value is either a BareItem
+// or a value which NewBareItem accepts.
+func (b *ListBuilder) AddItem(value interface{}) *ListBuilder {
+	return b.AddItemWithParams(value, nil)
+}
+
+// AddItemWithParams adds an Item with parameters. value is either
+// a BareItem or a value which NewBareItem accepts.
+func (b *ListBuilder) AddItemWithParams(value interface{}, params Parameters) *ListBuilder {
+	bi, err := builderBareItem(value)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("member %d: %w", len(b.list)+len(b.errs), err))
+		return b
+	}
+	if err := builderParameters(params); err != nil {
+		b.errs = append(b.errs, fmt.Errorf("member %d: %w", len(b.list)+len(b.errs), err))
+		return b
+	}
+	b.list = append(b.list, &member{val: &item{bareItem: bi, params: params}})
+	return b
+}
+
+// AddInnerList adds an InnerList.
+func (b *ListBuilder) AddInnerList(items []Item, params Parameters) *ListBuilder {
+	if err := builderInnerList(items, params); err != nil {
+		b.errs = append(b.errs, fmt.Errorf("member %d: %w", len(b.list)+len(b.errs), err))
+		return b
+	}
+	b.list = append(b.list, &member{val: &innerList{items: items, params: params}})
+	return b
+}
+
+// Build returns the built List, or an error which includes
+// all errors occurred while adding members.
+func (b *ListBuilder) Build() (List, error) {
+	if len(b.errs) > 0 {
+		return nil, joinBuilderErrors(b.errs)
+	}
+	return b.list, nil
+}
+
+// builderBareItem converts value to a BareItem with validating
+// its type and, for tokens, its value.
+func builderBareItem(value interface{}) (BareItem, error) {
+	bi, ok := value.(BareItem)
+	if !ok {
+		v, err := normalizeBareItemValue(value)
+		if err != nil {
+			return nil, err
+		}
+		bi = &bareItem{val: v}
+	}
+	if tok, ok := bi.TokenValue(); ok && !ValidToken(string(tok)) {
+		return nil, fmt.Errorf("invalid token: %q", tok)
+	}
+	return bi, nil
+}
+
+// builderParameters validates the keys and the values of params.
+func builderParameters(params Parameters) error {
+	if params == nil {
+		return nil
+	}
+	var err error
+	params.Range(func(name string, value BareItem) bool {
+		if !ValidKey(name) {
+			err = fmt.Errorf("invalid parameter key: %q", name)
+			return false
+		}
+		if _, err = builderBareItem(value); err != nil {
+			err = fmt.Errorf("parameter %q: %w", name, err)
+			return false
+		}
+		return true
+	})
+	return err
+}
+
+// builderInnerList validates the items of an inner list and
+// the parameters of the items and the inner list.
+func builderInnerList(items []Item, params Parameters) error {
+	for i, it := range items {
+		if it == nil {
+			return fmt.Errorf("item %d: nil item", i)
+		}
+		if _, err := builderBareItem(it.BareItem()); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+		if err := builderParameters(it.Parameters()); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+	return builderParameters(params)
+}
+
+func joinBuilderErrors(errs []error) error {
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
@@ -0,0 +1,108 @@
+package stheader_test
+
+import (
+	"fmt"
+	"testing"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestDictionaryBuilder(t *testing.T) {
+	params := stheader.NewParameters()
+	params.Store("p", stheader.NewBareItem(int64(1)))
+	items := []stheader.Item{
+		stheader.NewItem(stheader.NewBareItem(stheader.Token("x")), nil),
+		stheader.NewItem(stheader.NewBareItem("y"), nil),
+	}
+	dict, err := stheader.NewDictionaryBuilder().
+		AddItem("a", int64(1)).
+		AddItemWithParams("b", []byte("hi"), params).
+		AddInnerList("c", items, params).
+		AddItem("d", stheader.NewBareItem(false)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(dict), `a=1, b=*aGk=*;p=1, c=(x "y");p=1, d=?0`; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+}
+
+func TestDictionaryBuilderError(t *testing.T) {
+	badKeyParams := stheader.NewParameters()
+	badKeyParams.Store("P", stheader.NewBareItem(int64(1)))
+	badTokenParams := stheader.NewParameters()
+	badTokenParams.Store("p", stheader.NewBareItem(stheader.Token("1x")))
+	items := []stheader.Item{
+		stheader.NewItem(stheader.NewBareItem(int64(1)), badKeyParams),
+	}
+	_, err := stheader.NewDictionaryBuilder().
+		AddItem("a", int64(1)).
+		AddItem("B", int64(2)).
+		AddItem("c", int8(3)).
+		AddItem("a", int64(4)).
+		AddItem("d", stheader.Token("1x")).
+		AddItemWithParams("e", int64(5), badKeyParams).
+		AddItemWithParams("f", int64(6), badTokenParams).
+		AddInnerList("g", items, nil).
+		AddInnerList("h", nil, badTokenParams).
+		Build()
+	if err == nil {
+		t.Fatal("should fail")
+	}
+	want := `invalid key: "B"; key "c": invalid bare item type: int8; duplicate key: "a"; key "d": invalid token: "1x"; ` +
+		`key "e": invalid parameter key: "P"; key "f": parameter "p": invalid token: "1x"; ` +
+		`key "g": item 0: invalid parameter key: "P"; key "h": parameter "p": invalid token: "1x"`
+	if got := err.Error(); got != want {
+		t.Errorf("error mismatch,\n got=%s\nwant=%s", got, want)
+	}
+}
+
+func TestListBuilder(t *testing.T) {
+	params := stheader.NewParameters()
+	params.Store("q", stheader.NewBareItem(0.5))
+	items := []stheader.Item{
+		stheader.NewItem(stheader.NewBareItem(int64(1)), nil),
+		stheader.NewItem(stheader.NewBareItem(int64(2)), nil),
+	}
+	list, err := stheader.NewListBuilder().
+		AddItem(int64(1)).
+		AddItem("foo").
+		AddItem(true).
+		AddItemWithParams(stheader.Token("bar"), params).
+		AddItem([]byte("hi")).
+		AddItem(1.5).
+		AddInnerList(items, params).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(list), `1, "foo", ?1, bar;q=0.5, *aGk=*, 1.5, (1 2);q=0.5`; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+}
+
+func TestListBuilderError(t *testing.T) {
+	badKeyParams := stheader.NewParameters()
+	badKeyParams.Store("P", stheader.NewBareItem(int64(1)))
+	items := []stheader.Item{
+		stheader.NewItem(stheader.NewBareItem(int64(1)), nil),
+		stheader.NewItem(stheader.NewBareItem(stheader.Token("1x")), nil),
+	}
+	_, err := stheader.NewListBuilder().
+		AddItem(int64(1)).
+		AddItem(struct{}{}).
+		AddItem(stheader.Token("")).
+		AddItemWithParams(int64(2), badKeyParams).
+		AddInnerList(items, nil).
+		AddInnerList(nil, badKeyParams).
+		Build()
+	if err == nil {
+		t.Fatal("should fail")
+	}
+	want := `member 1: invalid bare item type: struct {}; member 2: invalid token: ""; ` +
+		`member 3: invalid parameter key: "P"; member 4: item 1: invalid token: "1x"; member 5: invalid parameter key: "P"`
+	if got := err.Error(); got != want {
+		t.Errorf("error mismatch,\n got=%s\nwant=%s", got, want)
+	}
+}
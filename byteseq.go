@@ -0,0 +1,98 @@
+package stheader
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"strconv"
+)
+
+// ByteSeqEncoding is the encoding of "Byte Sequence" values used by
+// Parser and Serializer.
+type ByteSeqEncoding int
+
+const (
+	// ByteSeqBase64 is base64 as the specification defines.
+	// This is the default.
+	ByteSeqBase64 ByteSeqEncoding = iota
+
+	// ByteSeqBase32 is base32 with the standard alphabet of RFC 4648.
+	// It is not valid in HTTP headers, and is only for interoperation
+	// with systems which exchange "Byte Sequence" values in base32,
+	// such as the JSON files of the HTTPWG tests.
+	ByteSeqBase32
+)
+
+// String returns the string representation for ByteSeqEncoding.
+// It returns "ByteSeqEncoding(n)" for unknown values.
+func (e ByteSeqEncoding) String() string {
+	switch e {
+	case ByteSeqBase64:
+		return "base64"
+	case ByteSeqBase32:
+		return "base32"
+	default:
+		return "ByteSeqEncoding(" + strconv.Itoa(int(e)) + ")"
+	}
+}
+
+// byteSeqCodec is the common interface of base64.Encoding and
+// base32.Encoding.
+type byteSeqCodec interface {
+	EncodedLen(n int) int
+	Encode(dst, src []byte)
+	DecodedLen(n int) int
+	Decode(dst, src []byte) (n int, err error)
+}
+
+var rawBase32Encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// codec returns the codec for encoded data of length n. Unpadded
+// codecs are returned for lengths which are not multiples of the
+// block length. ok is false if no encoded data has length n.
+func (e ByteSeqEncoding) codec(n int) (c byteSeqCodec, padded, ok bool) {
+	if e == ByteSeqBase32 {
+		switch n % 8 {
+		case 0:
+			return base32.StdEncoding, true, true
+		case 1, 3, 6:
+			return nil, false, false
+		default:
+			return rawBase32Encoding, false, true
+		}
+	}
+	switch n % 4 {
+	case 0:
+		return base64.StdEncoding, true, true
+	case 1:
+		return nil, false, false
+	default:
+		return base64.RawStdEncoding, false, true
+	}
+}
+
+// decodedLen returns the length of the data decoded from src, which
+// is computed from the length of src without the trailing padding.
+// It is exact for valid src.
+func (e ByteSeqEncoding) decodedLen(src []byte) int {
+	n := len(src)
+	for n > 0 && src[n-1] == '=' {
+		n--
+	}
+	if e == ByteSeqBase32 {
+		return rawBase32Encoding.DecodedLen(n)
+	}
+	return base64.RawStdEncoding.DecodedLen(n)
+}
+
+// corruptInputOffset returns the offset in the error returned by
+// Decode of base64 and base32 codecs.
+func corruptInputOffset(err error) (int64, bool) {
+	switch e := err.(type) {
+	case base64.CorruptInputError:
+		return int64(e), true
+	case base32.CorruptInputError:
+		return int64(e), true
+	default:
+		return 0, false
+	}
+}
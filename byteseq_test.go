@@ -0,0 +1,199 @@
+package stheader_test
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"runtime"
+	"testing"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestByteSeqEncodingRoundTrip(t *testing.T) {
+	data := []byte("pretty please")
+	testCases := []struct {
+		enc  stheader.ByteSeqEncoding
+		want string
+	}{
+		{enc: stheader.ByteSeqBase64, want: `*cHJldHR5IHBsZWFzZQ==*`},
+		{enc: stheader.ByteSeqBase32, want: `*OBZGK5DUPEQHA3DFMFZWK===*`},
+	}
+	for _, c := range testCases {
+		s := stheader.NewSerializer(stheader.WithByteSeqEncoding(c.enc))
+		got, err := s.SerializeItem(stheader.NewItem(stheader.NewBareItem(data), nil))
+		if err != nil {
+			t.Fatalf("unexpected error for %v: %s", c.enc, err)
+		}
+		if got != c.want {
+			t.Errorf("result mismatch for %v, got=%q, want=%q", c.enc, got, c.want)
+		}
+
+		p := stheader.NewParser(got)
+		p.ByteSeqEncoding = c.enc
+		item, err := p.ParseItem()
+		if err != nil {
+			t.Fatalf("unexpected error for %v: %s", c.enc, err)
+		}
+		if got := item.BareItem().AsByteSeq(); !bytes.Equal(got, data) {
+			t.Errorf("data mismatch for %v, got=%q, want=%q", c.enc, got, data)
+		}
+	}
+}
+
+func TestParseByteSeqBase32(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: `*MZXW6===*`, want: "foo"},
+		{input: `*MZXW6*`, want: "foo"},
+		{input: `**`, want: ""},
+		{input: `*MZXW6==*`, wantErr: true},
+		{input: `*MZX*`, wantErr: true},
+		{input: `*mzxw6===*`, wantErr: true},
+		{input: `*MZXW1===*`, wantErr: true},
+	}
+	for _, c := range testCases {
+		p := stheader.NewParser(c.input)
+		p.ByteSeqEncoding = stheader.ByteSeqBase32
+		item, err := p.ParseItem()
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("should fail for %q", c.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", c.input, err)
+			continue
+		}
+		if got := string(item.BareItem().AsByteSeq()); got != c.want {
+			t.Errorf("result mismatch for %q, got=%q, want=%q", c.input, got, c.want)
+		}
+	}
+}
+
+func TestParseByteSeqHook(t *testing.T) {
+	errTooLong := errors.New("byte sequence too long")
+	hook := func(b []byte) ([]byte, error) {
+		if len(b) > 16 {
+			return nil, errTooLong
+		}
+		return bytes.ToUpper(b), nil
+	}
+	testCases := []struct {
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{data: "", want: ""},
+		{data: "sixteen bytes!!!", want: "SIXTEEN BYTES!!!"},
+		{data: "seventeen bytes!!", wantErr: true},
+	}
+	for _, c := range testCases {
+		input := "a=1, b=*" + base64.StdEncoding.EncodeToString([]byte(c.data)) + "*"
+		p := stheader.NewParser(input)
+		p.ByteSeqHook = hook
+		dict, err := p.ParseDictionary()
+		if c.wantErr {
+			var perr *stheader.ParseError
+			if !errors.As(err, &perr) || !errors.Is(err, errTooLong) {
+				t.Errorf("should fail with ParseError wrapping the hook error for %q, got=%v", c.data, err)
+			} else if got, want := perr.Pos(), 7; got != want {
+				t.Errorf("error position mismatch for %q, got=%d, want=%d", c.data, got, want)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", c.data, err)
+			continue
+		}
+		m, _ := dict.Load("b")
+		if got := string(m.AsItem().BareItem().AsByteSeq()); got != c.want {
+			t.Errorf("result mismatch for %q, got=%q, want=%q", c.data, got, c.want)
+		}
+	}
+}
+
+func TestParseMaxByteSeqLen(t *testing.T) {
+	testCases := []struct {
+		n       int
+		limit   int
+		wantErr bool
+	}{
+		{n: 31, limit: 32},
+		{n: 32, limit: 32},
+		{n: 33, limit: 32, wantErr: true},
+		{n: 1000, limit: 0},
+	}
+	for _, c := range testCases {
+		data := bytes.Repeat([]byte{'x'}, c.n)
+		for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding} {
+			input := "a;b=*" + enc.EncodeToString(data) + "*"
+			p := stheader.NewParser(input)
+			p.MaxByteSeqLen = c.limit
+			item, err := p.ParseItem()
+			if c.wantErr {
+				var perr *stheader.ParseError
+				if !errors.As(err, &perr) || !errors.Is(err, stheader.ErrTooLarge) {
+					t.Errorf("should fail with ErrTooLarge for %d bytes (limit=%d), got=%v", c.n, c.limit, err)
+				} else if got, want := perr.Pos(), 4; got != want {
+					t.Errorf("error position mismatch, got=%d, want=%d", got, want)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("unexpected error for %d bytes (limit=%d): %s", c.n, c.limit, err)
+				continue
+			}
+			v, _ := item.Param("b")
+			if got := v.AsByteSeq(); !bytes.Equal(got, data) {
+				t.Errorf("result mismatch for %d bytes (limit=%d), got=%q", c.n, c.limit, got)
+			}
+		}
+	}
+}
+
+func TestParseMaxByteSeqLenBase32(t *testing.T) {
+	for n := 0; n <= 10; n++ {
+		input := "*" + base32.StdEncoding.EncodeToString(bytes.Repeat([]byte{'x'}, n)) + "*"
+		for _, limit := range []int{n, n + 1, n - 1} {
+			if limit <= 0 {
+				continue
+			}
+			p := stheader.NewParser(input)
+			p.ByteSeqEncoding = stheader.ByteSeqBase32
+			p.MaxByteSeqLen = limit
+			_, err := p.ParseItem()
+			if wantErr := n > limit; wantErr != errors.Is(err, stheader.ErrTooLarge) {
+				t.Errorf("unexpected result for %d bytes (limit=%d): %v", n, limit, err)
+			} else if !wantErr && err != nil {
+				t.Errorf("unexpected error for %d bytes (limit=%d): %s", n, limit, err)
+			}
+		}
+	}
+}
+
+func TestParseMaxByteSeqLenNoAlloc(t *testing.T) {
+	const n = 1 << 20
+	input := []byte("*" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{'x'}, n)) + "*")
+	p := stheader.NewParserBytes(input)
+	p.MaxByteSeqLen = 16
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 0; i < 3; i++ {
+		p.ResetBytes(input)
+		if _, err := p.ParseItem(); !errors.Is(err, stheader.ErrTooLarge) {
+			t.Fatalf("should fail with ErrTooLarge, got=%v", err)
+		}
+	}
+	runtime.ReadMemStats(&after)
+	// Decoding would grow the buffer of the parser to n bytes.
+	if got := after.TotalAlloc - before.TotalAlloc; got >= n/2 {
+		t.Errorf("decode buffer should not be grown, allocated %d bytes", got)
+	}
+}
@@ -0,0 +1,34 @@
+package stheader
+
+import "fmt"
+
+// IsCanonical reports whether input is in the canonical form, that is,
+// serializing the parsed value results in the same string as input.
+// It returns false for redundant whitespace or non-canonical numbers.
+//
+// headerType is one of "item", "list" and "dictionary".
+// It returns an error if headerType is unknown or input cannot be
+// parsed.
+func IsCanonical(headerType string, input string) (bool, error) {
+	p := NewParser(input)
+	var value interface{}
+	var err error
+	switch headerType {
+	case "item":
+		value, err = p.ParseItem()
+	case "list":
+		value, err = p.ParseList()
+	case "dictionary":
+		value, err = p.ParseDictionary()
+	default:
+		return false, fmt.Errorf("unknown header type: %s", headerType)
+	}
+	if err != nil {
+		return false, err
+	}
+	serialized, err := Serialize(value)
+	if err != nil {
+		return false, nil
+	}
+	return serialized == input, nil
+}
@@ -0,0 +1,43 @@
+package stheader_test
+
+import (
+	"testing"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestIsCanonical(t *testing.T) {
+	testCases := []struct {
+		headerType string
+		input      string
+		want       bool
+	}{
+		{headerType: "item", input: `1;a=2`, want: true},
+		{headerType: "item", input: ` 1`, want: false},
+		{headerType: "item", input: `01`, want: false},
+		{headerType: "item", input: `1.50`, want: false},
+		{headerType: "item", input: `1.5`, want: true},
+		{headerType: "list", input: `a, b, (c d)`, want: true},
+		{headerType: "list", input: `a,b`, want: false},
+		{headerType: "list", input: `(c  d)`, want: false},
+		{headerType: "dictionary", input: `a=1, b=?0`, want: true},
+		{headerType: "dictionary", input: `a=1,  b=?0`, want: false},
+	}
+	for _, c := range testCases {
+		got, err := stheader.IsCanonical(c.headerType, c.input)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", c.input, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("result mismatch for %q, got=%v, want=%v", c.input, got, c.want)
+		}
+	}
+
+	if _, err := stheader.IsCanonical("list", `a,`); err == nil {
+		t.Error("should fail for unparsable input")
+	}
+	if _, err := stheader.IsCanonical("foo", `a`); err == nil {
+		t.Error("should fail for an unknown header type")
+	}
+}
@@ -0,0 +1,97 @@
+package stheader
+
+// CloneDictionary returns a deep copy of dict.
+// Modifying the copy never affects the original.
+func CloneDictionary(dict Dictionary) Dictionary {
+	if dict == nil {
+		return nil
+	}
+	ret := &dictionary{items: make([]dictItem, 0, dict.Len())}
+	dict.Range(func(name string, value Member) bool {
+		ret.items = append(ret.items, dictItem{name: name, value: CloneMember(value)})
+		return true
+	})
+	return ret
+}
+
+// CloneList returns a deep copy of list.
+func CloneList(list List) List {
+	if list == nil {
+		return nil
+	}
+	ret := make(List, len(list))
+	for i, m := range list {
+		ret[i] = CloneMember(m)
+	}
+	return ret
+}
+
+// CloneMember returns a deep copy of m.
+func CloneMember(m Member) Member {
+	if m == nil {
+		return nil
+	}
+	switch m.Type() {
+	case MemberTypeItem:
+		return &member{val: CloneItem(m.AsItem())}
+	default:
+		return &member{val: CloneInnerList(m.AsInnerList())}
+	}
+}
+
+// CloneInnerList returns a deep copy of list.
+func CloneInnerList(list InnerList) InnerList {
+	if list == nil {
+		return nil
+	}
+	var items []Item
+	if src := list.Items(); src != nil {
+		items = make([]Item, len(src))
+		for i, it := range src {
+			items[i] = CloneItem(it)
+		}
+	}
+	return &innerList{
+		items:  items,
+		params: CloneParameters(list.Parameters()),
+	}
+}
+
+// CloneItem returns a deep copy of it.
+func CloneItem(it Item) Item {
+	if it == nil {
+		return nil
+	}
+	return &item{
+		bareItem: CloneBareItem(it.BareItem()),
+		params:   CloneParameters(it.Parameters()),
+	}
+}
+
+// CloneParameters returns a deep copy of params.
+func CloneParameters(params Parameters) Parameters {
+	if params == nil {
+		return nil
+	}
+	ret := &parameters{items: make([]paramItem, 0, params.Len())}
+	params.Range(func(name string, value BareItem) bool {
+		ret.items = append(ret.items, paramItem{name: name, value: CloneBareItem(value)})
+		return true
+	})
+	return ret
+}
+
+// CloneBareItem returns a copy of bi.
+// The "Byte Sequence" value is copied, not shared with bi.
+func CloneBareItem(bi BareItem) BareItem {
+	if bi == nil {
+		return nil
+	}
+	if bi.Type() == ItemTypeByteSeq {
+		src := bi.AsByteSeq()
+		dst := make([]byte, len(src))
+		copy(dst, src)
+		return &bareItem{val: dst}
+	}
+	return bi
+}
@@ -0,0 +1,84 @@
+package stheader_test
+
+import (
+	"fmt"
+	"testing"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestCloneDictionary(t *testing.T) {
+	const input = `a=*aGVsbG8=*;p=1, b=(*aGVsbG8=* 2);q, c=?0`
+	dict, err := stheader.NewParser(input).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := stheader.CloneDictionary(dict)
+
+	a, _ := clone.Load("a")
+	a.AsItem().BareItem().AsByteSeq()[0] = 'j'
+	a.AsItem().Parameters().Store("p", stheader.NewBareItem(int64(2)))
+	b, _ := clone.Load("b")
+	b.AsInnerList().Items()[0].BareItem().AsByteSeq()[0] = 'j'
+	b.AsInnerList().Parameters().Delete("q")
+	clone.Delete("c")
+	clone.Store("d", a)
+
+	if got := fmt.Sprint(dict); got != input {
+		t.Errorf("original modified, got=%q, want=%q", got, input)
+	}
+	if got, want := fmt.Sprint(clone), `a=*amVsbG8=*;p=2, b=(*amVsbG8=* 2), d=*amVsbG8=*;p=2`; got != want {
+		t.Errorf("clone mismatch, got=%q, want=%q", got, want)
+	}
+}
+
+func TestCloneList(t *testing.T) {
+	const input = `*aGVsbG8=*, (1 *aGVsbG8=*)`
+	list, err := stheader.NewParser(input).ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := stheader.CloneList(list)
+	clone[0].AsItem().BareItem().AsByteSeq()[0] = 'j'
+	clone[1].AsInnerList().Items()[1].BareItem().AsByteSeq()[0] = 'j'
+	clone[0] = clone[1]
+
+	if got := fmt.Sprint(list); got != input {
+		t.Errorf("original modified, got=%q, want=%q", got, input)
+	}
+}
+
+func TestParametersClone(t *testing.T) {
+	item, err := stheader.NewParser(`1;a=*aGk=*;b=2`).ParseItem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := item.Parameters()
+	clone := params.Clone()
+	if !stheader.EqualParameters(params, clone) {
+		t.Error("clone should be equal to the original")
+	}
+
+	a, _ := clone.Load("a")
+	a.AsByteSeq()[0] = 'j'
+	clone.Store("b", stheader.NewBareItem(3))
+	clone.Store("c", stheader.NewBareItem(4))
+	if got, want := fmt.Sprint(item), `1;a=*aGk=*;b=2`; got != want {
+		t.Errorf("original modified, got=%q, want=%q", got, want)
+	}
+
+	reordered := stheader.NewParameters()
+	params.Range(func(name string, value stheader.BareItem) bool {
+		reordered.Store(name, value)
+		return true
+	})
+	if !stheader.EqualParameters(params, reordered) {
+		t.Error("parameters in the same order should be equal")
+	}
+	reordered.Delete("a")
+	a, _ = params.Load("a")
+	reordered.Store("a", a)
+	if stheader.EqualParameters(params, reordered) {
+		t.Error("parameters in a different order should not be equal")
+	}
+}
@@ -0,0 +1,98 @@
+package stheader
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	decimalFracDigits  = 3
+	decimalIntDigits   = 12
+	maxDecimalMilliAbs = 999_999_999_999_999
+)
+
+// Decimal is the type of "Decimal" values, which are numbers with
+// at most three fractional digits.
+// It holds the value in thousandths, so it is formatted exactly.
+type Decimal struct {
+	milli int64
+}
+
+// NewDecimal creates a Decimal from a value in thousandths.
+// For example, NewDecimal(3142) represents 3.142.
+func NewDecimal(milli int64) Decimal {
+	return Decimal{milli: milli}
+}
+
+// Milli returns the value in thousandths.
+func (d Decimal) Milli() int64 {
+	return d.milli
+}
+
+// Float64 returns the value as float64.
+func (d Decimal) Float64() float64 {
+	return float64(d.milli) / 1000
+}
+
+// String returns the string representation for Decimal, which has
+// at least one fractional digit.
+func (d Decimal) String() string {
+	return string(appendMilli(nil, d.milli))
+}
+
+// roundToMilli converts unsigned decimal digits to a value in
+// thousandths with round-half-to-even.
+func roundToMilli(intPart, fracPart string) (int64, error) {
+	var kept, dropped string
+	if len(fracPart) > decimalFracDigits {
+		kept, dropped = fracPart[:decimalFracDigits], fracPart[decimalFracDigits:]
+	} else {
+		kept = fracPart + strings.Repeat("0", decimalFracDigits-len(fracPart))
+	}
+	milli, err := strconv.ParseInt(intPart+kept, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if roundUpHalfEven(milli, dropped) {
+		milli++
+	}
+	return milli, nil
+}
+
+// roundUpHalfEven reports whether kept must be incremented when
+// the digits in dropped are discarded with round-half-to-even.
+func roundUpHalfEven(kept int64, dropped string) bool {
+	if dropped == "" {
+		return false
+	}
+	switch {
+	case dropped[0] > '5':
+		return true
+	case dropped[0] < '5':
+		return false
+	}
+	if strings.TrimRight(dropped[1:], "0") != "" {
+		return true
+	}
+	return kept%2 == 1
+}
+
+// appendMilli appends a number which is represented in thousandths
+// with at least one and at most three fractional digits.
+func appendMilli(b []byte, milli int64) []byte {
+	if milli < 0 {
+		b = append(b, '-')
+		milli = -milli
+	}
+	b = strconv.AppendInt(b, milli/1000, 10)
+	b = append(b, '.')
+	frac := milli % 1000
+	b = append(b, byte('0'+frac/100))
+	if frac%100 != 0 {
+		b = append(b, byte('0'+frac/10%10))
+		if frac%10 != 0 {
+			b = append(b, byte('0'+frac%10))
+		}
+	}
+	return b
+}
@@ -0,0 +1,199 @@
+package stheader
+
+import "bytes"
+
+// Equal reports whether a and b are semantically equal.
+// a and b must be Dictionary, List, Member, Item, InnerList,
+// Parameters or BareItem values, otherwise it returns false.
+//
+// Dictionaries and parameters are compared as ordered maps, so
+// the same entries in a different order are not equal.
+// Nil and empty parameters are equal.
+func Equal(a, b interface{}) bool {
+	switch av := a.(type) {
+	case Dictionary:
+		bv, ok := b.(Dictionary)
+		return ok && EqualDictionary(av, bv)
+	case List:
+		bv, ok := b.(List)
+		return ok && EqualList(av, bv)
+	case Member:
+		bv, ok := b.(Member)
+		return ok && EqualMember(av, bv)
+	case Item:
+		bv, ok := b.(Item)
+		return ok && EqualItem(av, bv)
+	case InnerList:
+		bv, ok := b.(InnerList)
+		return ok && EqualInnerList(av, bv)
+	case Parameters:
+		bv, ok := b.(Parameters)
+		return ok && EqualParameters(av, bv)
+	case BareItem:
+		bv, ok := b.(BareItem)
+		return ok && EqualBareItem(av, bv)
+	default:
+		return false
+	}
+}
+
+// EqualDictionary reports whether a and b have the same entries
+// in the same order.
+func EqualDictionary(a, b Dictionary) bool {
+	if dictionaryLen(a) != dictionaryLen(b) {
+		return false
+	}
+	if dictionaryLen(a) == 0 {
+		return true
+	}
+	var names []string
+	var values []Member
+	b.Range(func(name string, value Member) bool {
+		names = append(names, name)
+		values = append(values, value)
+		return true
+	})
+	i := 0
+	equal := true
+	a.Range(func(name string, value Member) bool {
+		if name != names[i] || !EqualMember(value, values[i]) {
+			equal = false
+			return false
+		}
+		i++
+		return true
+	})
+	return equal
+}
+
+// EqualList reports whether a and b have the equal members.
+func EqualList(a, b List) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !EqualMember(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// EqualMember reports whether a and b are equal.
+func EqualMember(a, b Member) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	switch a.Type() {
+	case MemberTypeItem:
+		return EqualItem(a.AsItem(), b.AsItem())
+	default:
+		return EqualInnerList(a.AsInnerList(), b.AsInnerList())
+	}
+}
+
+// EqualInnerList reports whether a and b have the equal items
+// and parameters.
+func EqualInnerList(a, b InnerList) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ai, bi := a.Items(), b.Items()
+	if len(ai) != len(bi) {
+		return false
+	}
+	for i := range ai {
+		if !EqualItem(ai[i], bi[i]) {
+			return false
+		}
+	}
+	return EqualParameters(a.Parameters(), b.Parameters())
+}
+
+// EqualItem reports whether a and b have the equal bare items
+// and parameters.
+func EqualItem(a, b Item) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return EqualBareItem(a.BareItem(), b.BareItem()) &&
+		EqualParameters(a.Parameters(), b.Parameters())
+}
+
+// EqualParameters reports whether a and b have the same entries
+// in the same order.
+func EqualParameters(a, b Parameters) bool {
+	if parametersLen(a) != parametersLen(b) {
+		return false
+	}
+	if parametersLen(a) == 0 {
+		return true
+	}
+	var names []string
+	var values []BareItem
+	b.Range(func(name string, value BareItem) bool {
+		names = append(names, name)
+		values = append(values, value)
+		return true
+	})
+	i := 0
+	equal := true
+	a.Range(func(name string, value BareItem) bool {
+		if name != names[i] || !EqualBareItem(value, values[i]) {
+			equal = false
+			return false
+		}
+		i++
+		return true
+	})
+	return equal
+}
+
+// EqualBareItem reports whether a and b have the same type and value.
+func EqualBareItem(a, b BareItem) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	switch a.Type() {
+	case ItemTypeString:
+		return a.AsString() == b.AsString()
+	case ItemTypeByteSeq:
+		return bytes.Equal(a.AsByteSeq(), b.AsByteSeq())
+	case ItemTypeBool:
+		return a.AsBool() == b.AsBool()
+	case ItemTypeInt:
+		return a.AsInt() == b.AsInt()
+	case ItemTypeFloat:
+		return a.AsFloat() == b.AsFloat()
+	case ItemTypeToken:
+		return a.AsToken() == b.AsToken()
+	case ItemTypeDecimal:
+		return a.AsDecimal() == b.AsDecimal()
+	case ItemTypeDate:
+		return a.AsDate().Equal(b.AsDate())
+	case ItemTypeDisplayString:
+		return a.AsDisplayString() == b.AsDisplayString()
+	default:
+		return false
+	}
+}
+
+func dictionaryLen(d Dictionary) int {
+	if d == nil {
+		return 0
+	}
+	return d.Len()
+}
+
+func parametersLen(p Parameters) int {
+	if p == nil {
+		return 0
+	}
+	return p.Len()
+}
@@ -0,0 +1,63 @@
+package stheader_test
+
+import (
+	"testing"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestEqual(t *testing.T) {
+	testCases := []struct {
+		a, b       string
+		headerType string
+		want       bool
+	}{
+		{a: `a=1, b=(x y);p=*aGk=*`, b: `a=1,b=(x  y);p=*aGk=*`, headerType: "dictionary", want: true},
+		{a: `a=1, b=2`, b: `b=2, a=1`, headerType: "dictionary", want: false},
+		{a: `a=1`, b: `a=1.0`, headerType: "dictionary", want: false},
+		{a: `a=foo`, b: `a="foo"`, headerType: "dictionary", want: false},
+		{a: `1;a;b=2, (x y)`, b: `1;a;b=2, (x y)`, headerType: "list", want: true},
+		{a: `1;a;b=2`, b: `1;b=2;a`, headerType: "list", want: false},
+		{a: `*aGk=*`, b: `*aGk=*`, headerType: "item", want: true},
+		{a: `*aGk=*`, b: `*aGo=*`, headerType: "item", want: false},
+	}
+	for _, c := range testCases {
+		a, err := parse(c.headerType, c.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := parse(c.headerType, c.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := stheader.Equal(a, b); got != c.want {
+			t.Errorf("Equal(%q, %q) mismatch, got=%v, want=%v", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestEqualParameters(t *testing.T) {
+	item := stheader.NewItem(stheader.NewBareItem(int64(1)), nil)
+	parsed, err := stheader.NewParser("1").ParseItem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stheader.EqualItem(item, parsed) {
+		t.Error("nil and empty parameters should be equal")
+	}
+	if stheader.Equal(item, stheader.List{}) {
+		t.Error("values of different kinds should not be equal")
+	}
+}
+
+func parse(headerType, input string) (interface{}, error) {
+	p := stheader.NewParser(input)
+	switch headerType {
+	case "dictionary":
+		return p.ParseDictionary()
+	case "list":
+		return p.ParseList()
+	default:
+		return p.ParseItem()
+	}
+}
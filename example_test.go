@@ -0,0 +1,64 @@
+package stheader_test
+
+import (
+	"fmt"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func ExampleParser_ParseDictionary() {
+	dict, err := stheader.NewParser(`a=?0, b, c;foo=bar`).ParseDictionary()
+	if err != nil {
+		panic(err)
+	}
+	dict.Range(func(name string, value stheader.Member) bool {
+		item := value.AsItem()
+		fmt.Printf("%s: %v", name, item.BareItem().AsBool())
+		item.Parameters().Range(func(name string, value stheader.BareItem) bool {
+			fmt.Printf(" (%s=%v)", name, value)
+			return true
+		})
+		fmt.Println()
+		return true
+	})
+	// Output:
+	// a: false
+	// b: true
+	// c: true (foo=bar)
+}
+
+func ExampleParser_ParseList() {
+	list, err := stheader.NewParser(`sugar, tea, ("rum" 42);strong`).ParseList()
+	if err != nil {
+		panic(err)
+	}
+	for _, m := range list {
+		switch m.Type() {
+		case stheader.MemberTypeItem:
+			fmt.Printf("item %s: %v\n", m.AsItem().BareItem().Type(), m)
+		case stheader.MemberTypeInnerList:
+			fmt.Printf("inner list of %d items: %v\n", len(m.AsInnerList().Items()), m)
+		}
+	}
+	// Output:
+	// item token: sugar
+	// item token: tea
+	// inner list of 2 items: ("rum" 42);strong
+}
+
+func ExampleSerialize() {
+	params := stheader.NewParameters()
+	params.Store("q", stheader.NewBareItem(0.5))
+	dict := stheader.NewDictionary()
+	dict.Store("lang", stheader.NewMember(stheader.NewItem(stheader.NewBareItem("en"), params)))
+	dict.Store("cache", stheader.NewMember(stheader.NewItem(stheader.NewBareItem(true), nil)))
+	dict.Store("data", stheader.NewMember(stheader.NewItem(stheader.NewBareItem([]byte("hello")), nil)))
+
+	s, err := stheader.Serialize(dict)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(s)
+	// Output:
+	// lang="en";q=0.5, cache, data=*aGVsbG8=*
+}
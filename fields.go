@@ -0,0 +1,53 @@
+package stheader
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fieldSeparator is the separator to combine multiple field lines.
+const fieldSeparator = ", "
+
+// ParseListFields combines multiple field lines of a header and
+// parses it as List.
+func ParseListFields(fields []string) (List, error) {
+	return NewParser(combineFields(fields)).ParseList()
+}
+
+// ParseDictionaryFields combines multiple field lines of a header and
+// parses it as Dictionary.
+func ParseDictionaryFields(fields []string) (Dictionary, error) {
+	return NewParser(combineFields(fields)).ParseDictionary()
+}
+
+// ParseItemFields parses field lines of a header as Item.
+// It returns a *ParseError if more than one non-empty field line is
+// given since an Item cannot span multiple field lines. Its position
+// is the start of the second non-empty field line in the combined
+// field value.
+func ParseItemFields(fields []string) (Item, error) {
+	nonEmpty := nonEmptyFields(fields)
+	if len(nonEmpty) > 1 {
+		pos := len(nonEmpty[0]) + len(fieldSeparator)
+		return nil, &ParseError{
+			msg:  fmt.Sprintf("An item must not span multiple field lines on position %d", pos),
+			pos:  pos,
+			kind: ErrInvalidCharacter,
+		}
+	}
+	return NewParser(strings.Join(nonEmpty, "")).ParseItem()
+}
+
+func combineFields(fields []string) string {
+	return strings.Join(nonEmptyFields(fields), fieldSeparator)
+}
+
+func nonEmptyFields(fields []string) []string {
+	var ret []string
+	for _, f := range fields {
+		if strings.Trim(f, " \t") != "" {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
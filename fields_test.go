@@ -0,0 +1,89 @@
+package stheader_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestParseListFields(t *testing.T) {
+	list, err := stheader.ParseListFields([]string{"a, b", "", "c;x=1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(list), "a, b, c;x=1"; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+}
+
+func TestParseListFieldsInnerLists(t *testing.T) {
+	testCases := []struct {
+		fields  []string
+		want    string
+		wantErr bool
+	}{
+		{fields: []string{"(1 2)", "(3 4)"}, want: "(1 2), (3 4)"},
+		{fields: []string{"(1 2);a", "(3 4);b=?0"}, want: "(1 2);a, (3 4);b=?0"},
+		{fields: []string{" (1 2)\t", "\t(3 4) "}, want: "(1 2), (3 4)"},
+		{fields: []string{"(1 2), ()", "(3)"}, want: "(1 2), (), (3)"},
+		{fields: []string{"(1", "2)"}, wantErr: true},
+		{fields: []string{"(1 2", "3 4)"}, wantErr: true},
+		{fields: []string{"(1 2),", "(3 4)"}, wantErr: true},
+	}
+	for _, c := range testCases {
+		list, err := stheader.ParseListFields(c.fields)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("should fail for %q, got=%v", c.fields, list)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", c.fields, err)
+			continue
+		}
+		if got := fmt.Sprint(list); got != c.want {
+			t.Errorf("result mismatch for %q, got=%q, want=%q", c.fields, got, c.want)
+		}
+		for _, m := range list {
+			if m.Type() != stheader.MemberTypeInnerList {
+				t.Errorf("member should be an inner list for %q, got=%v", c.fields, m)
+			}
+		}
+	}
+}
+
+func TestParseDictionaryFields(t *testing.T) {
+	dict, err := stheader.ParseDictionaryFields([]string{"a=1", "b=2, c=3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(dict), "a=1, b=2, c=3"; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+
+	if _, err := stheader.ParseDictionaryFields([]string{"a=1", "a=2"}); err == nil {
+		t.Error("should fail for a duplicate key across field lines")
+	}
+}
+
+func TestParseItemFields(t *testing.T) {
+	item, err := stheader.ParseItemFields([]string{"", "1;a=2", " "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(item), "1;a=2"; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+
+	_, err = stheader.ParseItemFields([]string{"1;a", "", "2"})
+	var perr *stheader.ParseError
+	if !errors.As(err, &perr) || !errors.Is(err, stheader.ErrInvalidCharacter) {
+		t.Fatalf("should fail with ParseError for an item spanning multiple field lines, got=%v", err)
+	}
+	if got, want := perr.Pos(), 5; got != want {
+		t.Errorf("error position mismatch, got=%d, want=%d", got, want)
+	}
+}
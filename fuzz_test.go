@@ -0,0 +1,121 @@
+package stheader_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func FuzzParse(f *testing.F) {
+	seeds := []string{
+		`a=1, b=(1 2);x, c="foo"`,
+		`1;a=?1;b=*aGk=*`,
+		`(a b);c=%"f%c3%bc", @1659578233, 3.142`,
+		`-0.5, -`,
+		`**, *!!!*, :aGk=:`,
+	}
+	// The HTTPWG test inputs are used as seeds if available.
+	filenames, _ := filepath.Glob("structured-header-tests/*.json")
+	for _, filename := range filenames {
+		group, err := readHTTPWGTestGroupFile(filename)
+		if err != nil {
+			continue
+		}
+		for _, test := range group {
+			seeds = append(seeds, strings.Join(test.Raw, ", "))
+		}
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		for _, rfc8941 := range []bool{false, true} {
+			for _, headerType := range []string{"item", "list", "dictionary"} {
+				p := stheader.NewParserBytes(input)
+				p.RFC8941 = rfc8941
+				var err error
+				switch headerType {
+				case "item":
+					_, err = p.ParseItem()
+				case "list":
+					_, err = p.ParseList()
+				case "dictionary":
+					_, err = p.ParseDictionary()
+				}
+				var perr *stheader.ParseError
+				if err != nil && !errors.As(err, &perr) {
+					t.Errorf("error for %q as %s is not ParseError: %v", input, headerType, err)
+				}
+			}
+		}
+	})
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	seeds := []string{
+		`a=1, b=(1 2);x, c="foo"`,
+		`1;a=?1;b=*aGk=*`,
+		`(a b);c=%"f%c3%bc", @1659578233, 3.142`,
+		`a;x=1, b=?0, *aGk*`,
+		`-0.0, 1.5000, 007`,
+	}
+	// The canonical forms of the HTTPWG test inputs are used as seeds
+	// if available.
+	filenames, _ := filepath.Glob("structured-header-tests/*.json")
+	for _, filename := range filenames {
+		group, err := readHTTPWGTestGroupFile(filename)
+		if err != nil {
+			continue
+		}
+		for _, test := range group {
+			if len(test.Canonical) > 0 {
+				seeds = append(seeds, strings.Join(test.Canonical, ", "))
+			}
+		}
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		for _, rfc8941 := range []bool{false, true} {
+			for _, headerType := range []string{"item", "list", "dictionary"} {
+				p := stheader.NewParserBytes(input)
+				p.RFC8941 = rfc8941
+				first, err := parseAs(p, headerType)
+				if err != nil {
+					continue
+				}
+				s := stheader.Serializer{RFC8941: rfc8941}
+				serialized, err := s.Serialize(first)
+				if err != nil {
+					t.Fatalf("cannot serialize %q parsed as %s: %v", input, headerType, err)
+				}
+				p = stheader.NewParser(serialized)
+				p.RFC8941 = rfc8941
+				second, err := parseAs(p, headerType)
+				if err != nil {
+					t.Fatalf("cannot parse %q serialized from %q as %s: %v", serialized, input, headerType, err)
+				}
+				if !stheader.Equal(first, second) {
+					t.Fatalf("round trip mismatch for %q as %s, serialized=%q", input, headerType, serialized)
+				}
+			}
+		}
+	})
+}
+
+func parseAs(p *stheader.Parser, headerType string) (interface{}, error) {
+	switch headerType {
+	case "item":
+		return p.ParseItem()
+	case "list":
+		return p.ParseList()
+	default:
+		return p.ParseDictionary()
+	}
+}
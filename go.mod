@@ -1,3 +1,3 @@
 module gihtub.com/hnakamur/stheader
 
-go 1.13
+go 1.18
@@ -0,0 +1,62 @@
+package stheader
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrHeaderNotPresent is returned by ParseHTTP* functions when
+// the header is not present in http.Header.
+var ErrHeaderNotPresent = errors.New("stheader: header not present")
+
+// ParseHTTPList parses the values of the header name in h as List.
+// Multiple field lines are combined before parsing.
+// It returns ErrHeaderNotPresent if the header is absent.
+func ParseHTTPList(h http.Header, name string) (List, error) {
+	fields, err := httpHeaderValues(h, name)
+	if err != nil {
+		return nil, err
+	}
+	return ParseListFields(fields)
+}
+
+// ParseHTTPDictionary parses the values of the header name in h
+// as Dictionary. Multiple field lines are combined before parsing.
+// It returns ErrHeaderNotPresent if the header is absent.
+func ParseHTTPDictionary(h http.Header, name string) (Dictionary, error) {
+	fields, err := httpHeaderValues(h, name)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDictionaryFields(fields)
+}
+
+// ParseHTTPItem parses the value of the header name in h as Item.
+// It returns ErrHeaderNotPresent if the header is absent.
+func ParseHTTPItem(h http.Header, name string) (Item, error) {
+	fields, err := httpHeaderValues(h, name)
+	if err != nil {
+		return nil, err
+	}
+	return ParseItemFields(fields)
+}
+
+// SetHTTPHeader serializes value, which must be Dictionary, List or
+// Item, and sets it to the header name in h.
+// h is not modified if serialization fails.
+func SetHTTPHeader(h http.Header, name string, value interface{}) error {
+	v, err := Serialize(value)
+	if err != nil {
+		return err
+	}
+	h.Set(name, v)
+	return nil
+}
+
+func httpHeaderValues(h http.Header, name string) ([]string, error) {
+	fields := h.Values(name)
+	if fields == nil {
+		return nil, ErrHeaderNotPresent
+	}
+	return fields, nil
+}
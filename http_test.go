@@ -0,0 +1,87 @@
+package stheader_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestParseHTTP(t *testing.T) {
+	h := http.Header{}
+	h.Add("Example-List", "a, b")
+	h.Add("Example-List", "(c d)")
+	h.Add("Example-Dict", "a=1")
+	h.Add("Example-Dict", "b=?0")
+	h.Set("Example-Item", "1;p")
+	h.Set("Example-Empty", "")
+
+	list, err := stheader.ParseHTTPList(h, "example-list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(list), "a, b, (c d)"; got != want {
+		t.Errorf("list mismatch, got=%q, want=%q", got, want)
+	}
+
+	dict, err := stheader.ParseHTTPDictionary(h, "Example-Dict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(dict), "a=1, b=?0"; got != want {
+		t.Errorf("dictionary mismatch, got=%q, want=%q", got, want)
+	}
+
+	item, err := stheader.ParseHTTPItem(h, "Example-Item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(item), "1;p"; got != want {
+		t.Errorf("item mismatch, got=%q, want=%q", got, want)
+	}
+
+	list, err = stheader.ParseHTTPList(h, "Example-Empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Errorf("empty header should result in an empty list, got=%v", list)
+	}
+
+	if _, err := stheader.ParseHTTPList(h, "Missing"); err != stheader.ErrHeaderNotPresent {
+		t.Errorf("error mismatch, got=%v, want=%v", err, stheader.ErrHeaderNotPresent)
+	}
+}
+
+func TestSetHTTPHeader(t *testing.T) {
+	h := http.Header{}
+	list := stheader.List{
+		stheader.NewMember(stheader.NewItem(stheader.NewBareItem(stheader.Token("a")), nil)),
+		stheader.NewMember(stheader.NewItem(stheader.NewBareItem(int64(1)), nil)),
+	}
+	if err := stheader.SetHTTPHeader(h, "Example", list); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := h.Get("Example"), "a, 1"; got != want {
+		t.Errorf("header mismatch, got=%q, want=%q", got, want)
+	}
+
+	invalid := stheader.List{
+		stheader.NewMember(stheader.NewItem(stheader.NewBareItem(stheader.Token("1a")), nil)),
+	}
+	if err := stheader.SetHTTPHeader(h, "Example", invalid); err == nil {
+		t.Error("should fail for an invalid token")
+	}
+	if got, want := h.Get("Example"), "a, 1"; got != want {
+		t.Errorf("header should not be modified on failure, got=%q, want=%q", got, want)
+	}
+	dict := stheader.NewDictionary()
+	dict.Store("A", stheader.NewMember(stheader.NewItem(stheader.NewBareItem(true), nil)))
+	if err := stheader.SetHTTPHeader(h, "Other", dict); err == nil {
+		t.Error("should fail for an invalid key")
+	}
+	if _, ok := h["Other"]; ok {
+		t.Error("header should not be added on failure")
+	}
+}
@@ -3,6 +3,7 @@ package stheader_test
 import (
 	"encoding/base32"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -191,6 +192,91 @@ func TestSerializeHTTPWG(t *testing.T) {
 	}
 }
 
+func TestIsCanonicalHTTPWG(t *testing.T) {
+	groupNames := []string{
+		"binary",
+		"boolean",
+		"number",
+		"string",
+		"token",
+
+		"item",
+
+		"list",
+		"listlist",
+		"dictionary",
+		"param-list",
+	}
+	for _, groupName := range groupNames {
+		filename := fmt.Sprintf("structured-header-tests/%s.json", groupName)
+		group, err := readHTTPWGTestGroupFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, test := range group {
+			if test.MustFail || len(test.Raw) != 1 {
+				continue
+			}
+			subTestName := fmt.Sprintf("%s_%s", groupName, test.Name)
+			t.Run(subTestName, func(t *testing.T) {
+				got, err := stheader.IsCanonical(test.HeaderType, test.Raw[0])
+				if err != nil {
+					if !test.CanFail {
+						t.Errorf("should not have failed, but got error=%s", err)
+					}
+					return
+				}
+				want := len(test.Canonical) == 0 || test.Canonical[0] == test.Raw[0]
+				if got != want {
+					t.Errorf("unmatch, got=%v, want=%v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestParseErrorTypeHTTPWG(t *testing.T) {
+	groupNames := []string{
+		"binary",
+		"boolean",
+		"number",
+		"string",
+		"token",
+
+		"item",
+
+		"list",
+		"listlist",
+		"dictionary",
+		"param-list",
+
+		"key-generated",
+		"large-generated",
+		"string-generated",
+		"token-generated",
+	}
+	for _, groupName := range groupNames {
+		filename := fmt.Sprintf("structured-header-tests/%s.json", groupName)
+		group, err := readHTTPWGTestGroupFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, test := range group {
+			if !test.MustFail {
+				continue
+			}
+			subTestName := fmt.Sprintf("%s_%s", groupName, test.Name)
+			t.Run(subTestName, func(t *testing.T) {
+				input := strings.Join(test.Raw, ",")
+				_, err := parse(test.HeaderType, input)
+				if !errors.As(err, new(*stheader.ParseError)) {
+					t.Fatalf("should fail with ParseError, got=%v", err)
+				}
+			})
+		}
+	}
+}
+
 func convertBareItemToExpected(bi stheader.BareItem) interface{} {
 	switch bi.Type() {
 	case stheader.ItemTypeBool:
@@ -203,6 +289,8 @@ func convertBareItemToExpected(bi stheader.BareItem) interface{} {
 		return float64(bi.AsInt())
 	case stheader.ItemTypeFloat:
 		return bi.AsFloat()
+	case stheader.ItemTypeDecimal:
+		return bi.AsDecimal().Float64()
 	case stheader.ItemTypeToken:
 		return string(bi.AsToken())
 	default:
@@ -257,14 +345,7 @@ func convertInnerListToExpected(list stheader.InnerList) interface{} {
 func convertParametersToExpected(params stheader.Parameters) interface{} {
 	ret := make(map[string]interface{})
 	params.Range(func(key string, val stheader.BareItem) bool {
-		switch v := val.(type) {
-		case stheader.BareItem:
-			ret[key] = convertBareItemToExpected(v)
-		case nil:
-			ret[key] = nil
-		default:
-			panic("invalid param value type")
-		}
+		ret[key] = convertBareItemToExpected(val)
 		return true
 	})
 	return ret
@@ -0,0 +1,521 @@
+package stheader
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The JSON representation of structured values follows the schema of
+// the HTTP WG structured field tests:
+//
+//   - "String" values are JSON strings.
+//   - "Integer" values are JSON numbers without a fractional part.
+//   - "Decimal" and "Float" values are JSON numbers with a fractional part.
+//   - "Boolean" values are JSON booleans.
+//   - "Token" values are {"__type": "token", "value": "<token>"}.
+//   - "Byte Sequence" values are {"__type": "binary", "value": "<base64>"}.
+//   - "Date" values are {"__type": "date", "value": <seconds since epoch>}.
+//   - "Display String" values are {"__type": "displaystring", "value": "<string>"}.
+//   - Parameters are arrays of [name, value] pairs.
+//   - Items are [value, parameters].
+//   - Inner lists are [[item, ...], parameters].
+//   - Lists are arrays of items and inner lists.
+//   - Dictionaries are arrays of [name, item or inner list] pairs.
+//
+// Parameters and dictionaries are arrays rather than JSON objects
+// to keep the order of entries.
+//
+// MarshalJSON methods produce the JSON representation, and
+// Unmarshal*JSON functions read it back.
+
+const (
+	jsonTypeToken         = "token"
+	jsonTypeBinary        = "binary"
+	jsonTypeDate          = "date"
+	jsonTypeDisplayString = "displaystring"
+)
+
+type jsonTypedValue struct {
+	Type  string      `json:"__type"`
+	Value interface{} `json:"value"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (i *bareItem) MarshalJSON() ([]byte, error) {
+	v, err := bareItemJSONValue(i)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (i *item) MarshalJSON() ([]byte, error) {
+	v, err := itemJSONValue(i)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (l *innerList) MarshalJSON() ([]byte, error) {
+	v, err := innerListJSONValue(l)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m *member) MarshalJSON() ([]byte, error) {
+	v, err := memberJSONValue(m)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p *parameters) MarshalJSON() ([]byte, error) {
+	v, err := parametersJSONValue(p)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (l List) MarshalJSON() ([]byte, error) {
+	v, err := listJSONValue(l)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d *dictionary) MarshalJSON() ([]byte, error) {
+	v, err := dictionaryJSONValue(d)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func dictionaryJSONValue(dict Dictionary) (interface{}, error) {
+	ret := []interface{}{}
+	if dict == nil {
+		return ret, nil
+	}
+	var err error
+	dict.Range(func(name string, value Member) bool {
+		var v interface{}
+		v, err = memberJSONValue(value)
+		if err != nil {
+			return false
+		}
+		ret = append(ret, []interface{}{name, v})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func listJSONValue(list List) (interface{}, error) {
+	ret := make([]interface{}, len(list))
+	for i, m := range list {
+		v, err := memberJSONValue(m)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = v
+	}
+	return ret, nil
+}
+
+func memberJSONValue(m Member) (interface{}, error) {
+	if m == nil {
+		return nil, errors.New("nil member")
+	}
+	switch m.Type() {
+	case MemberTypeItem:
+		return itemJSONValue(m.AsItem())
+	case MemberTypeInnerList:
+		return innerListJSONValue(m.AsInnerList())
+	default:
+		return nil, errors.New("invalid member type")
+	}
+}
+
+func innerListJSONValue(list InnerList) (interface{}, error) {
+	if list == nil {
+		return nil, errors.New("nil inner list")
+	}
+	items := make([]interface{}, len(list.Items()))
+	for i, it := range list.Items() {
+		v, err := itemJSONValue(it)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = v
+	}
+	params, err := parametersJSONValue(list.Parameters())
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{items, params}, nil
+}
+
+func itemJSONValue(it Item) (interface{}, error) {
+	if it == nil {
+		return nil, errors.New("nil item")
+	}
+	v, err := bareItemJSONValue(it.BareItem())
+	if err != nil {
+		return nil, err
+	}
+	params, err := parametersJSONValue(it.Parameters())
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{v, params}, nil
+}
+
+func parametersJSONValue(params Parameters) (interface{}, error) {
+	ret := []interface{}{}
+	if params == nil {
+		return ret, nil
+	}
+	var err error
+	params.Range(func(name string, value BareItem) bool {
+		var v interface{}
+		v, err = bareItemJSONValue(value)
+		if err != nil {
+			return false
+		}
+		ret = append(ret, []interface{}{name, v})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func bareItemJSONValue(bi BareItem) (interface{}, error) {
+	if bi == nil {
+		return nil, errors.New("nil bare item")
+	}
+	switch bi.Type() {
+	case ItemTypeString:
+		return bi.AsString(), nil
+	case ItemTypeByteSeq:
+		return jsonTypedValue{
+			Type:  jsonTypeBinary,
+			Value: base64.StdEncoding.EncodeToString(bi.AsByteSeq()),
+		}, nil
+	case ItemTypeBool:
+		return bi.AsBool(), nil
+	case ItemTypeInt:
+		return bi.AsInt(), nil
+	case ItemTypeFloat:
+		b, err := appendBareItemFloat(nil, bi.AsFloat())
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(b), nil
+	case ItemTypeToken:
+		return jsonTypedValue{Type: jsonTypeToken, Value: string(bi.AsToken())}, nil
+	case ItemTypeDecimal:
+		return json.Number(bi.AsDecimal().String()), nil
+	case ItemTypeDate:
+		return jsonTypedValue{Type: jsonTypeDate, Value: bi.AsDate().Unix()}, nil
+	case ItemTypeDisplayString:
+		return jsonTypedValue{
+			Type:  jsonTypeDisplayString,
+			Value: string(bi.AsDisplayString()),
+		}, nil
+	default:
+		return nil, errors.New("invalid item type")
+	}
+}
+
+// UnmarshalDictionaryJSON reads the JSON representation of a Dictionary.
+func UnmarshalDictionaryJSON(data []byte) (Dictionary, error) {
+	v, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return dictionaryFromJSON(v)
+}
+
+// UnmarshalListJSON reads the JSON representation of a List.
+func UnmarshalListJSON(data []byte) (List, error) {
+	v, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return listFromJSON(v)
+}
+
+// UnmarshalItemJSON reads the JSON representation of an Item.
+func UnmarshalItemJSON(data []byte) (Item, error) {
+	v, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return itemFromJSON(v)
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+func dictionaryFromJSON(v interface{}) (Dictionary, error) {
+	entries, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.New("dictionary must be a JSON array")
+	}
+	dict := &dictionary{}
+	for _, e := range entries {
+		name, value, err := jsonPair(e, "dictionary")
+		if err != nil {
+			return nil, err
+		}
+		if dict.index(name) != -1 {
+			return nil, fmt.Errorf("duplicate key in dictionary: %s", name)
+		}
+		m, err := memberFromJSON(value)
+		if err != nil {
+			return nil, err
+		}
+		dict.Store(name, m)
+	}
+	return dict, nil
+}
+
+func listFromJSON(v interface{}) (List, error) {
+	members, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.New("list must be a JSON array")
+	}
+	list := make(List, len(members))
+	for i, mv := range members {
+		m, err := memberFromJSON(mv)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = m
+	}
+	return list, nil
+}
+
+func memberFromJSON(v interface{}) (Member, error) {
+	arr, ok := v.([]interface{})
+	if !ok || len(arr) != 2 {
+		return nil, errors.New("item or inner list must be a JSON array of two elements")
+	}
+	if _, ok := arr[0].([]interface{}); ok {
+		list, err := innerListFromJSON(arr)
+		if err != nil {
+			return nil, err
+		}
+		return &member{val: list}, nil
+	}
+	it, err := itemFromJSON(arr)
+	if err != nil {
+		return nil, err
+	}
+	return &member{val: it}, nil
+}
+
+func innerListFromJSON(v interface{}) (InnerList, error) {
+	arr, ok := v.([]interface{})
+	if !ok || len(arr) != 2 {
+		return nil, errors.New("inner list must be a JSON array of two elements")
+	}
+	itemValues, ok := arr[0].([]interface{})
+	if !ok {
+		return nil, errors.New("items of inner list must be a JSON array")
+	}
+	items := make([]Item, len(itemValues))
+	for i, iv := range itemValues {
+		it, err := itemFromJSON(iv)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = it
+	}
+	params, err := parametersFromJSON(arr[1])
+	if err != nil {
+		return nil, err
+	}
+	return &innerList{items: items, params: params}, nil
+}
+
+func itemFromJSON(v interface{}) (Item, error) {
+	arr, ok := v.([]interface{})
+	if !ok || len(arr) != 2 {
+		return nil, errors.New("item must be a JSON array of two elements")
+	}
+	bi, err := bareItemFromJSON(arr[0])
+	if err != nil {
+		return nil, err
+	}
+	params, err := parametersFromJSON(arr[1])
+	if err != nil {
+		return nil, err
+	}
+	return &item{bareItem: bi, params: params}, nil
+}
+
+func parametersFromJSON(v interface{}) (Parameters, error) {
+	entries, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.New("parameters must be a JSON array")
+	}
+	params := &parameters{}
+	for _, e := range entries {
+		name, value, err := jsonPair(e, "parameters")
+		if err != nil {
+			return nil, err
+		}
+		if params.index(name) != -1 {
+			return nil, fmt.Errorf("duplicate parameter key: %s", name)
+		}
+		var bi BareItem
+		if value != nil {
+			bi, err = bareItemFromJSON(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		params.Store(name, bi)
+	}
+	return params, nil
+}
+
+// jsonPair returns the name and the value of an entry of
+// dictionaries or parameters.
+func jsonPair(v interface{}, context string) (string, interface{}, error) {
+	pair, ok := v.([]interface{})
+	if !ok || len(pair) != 2 {
+		return "", nil, fmt.Errorf("entry of %s must be a JSON array of name and value", context)
+	}
+	name, ok := pair[0].(string)
+	if !ok || !ValidKey(name) {
+		return "", nil, fmt.Errorf("invalid key in %s: %v", context, pair[0])
+	}
+	return name, pair[1], nil
+}
+
+func bareItemFromJSON(v interface{}) (BareItem, error) {
+	switch val := v.(type) {
+	case string:
+		return &bareItem{val: val}, nil
+	case bool:
+		return &bareItem{val: val}, nil
+	case json.Number:
+		return numberFromJSON(val)
+	case map[string]interface{}:
+		return typedValueFromJSON(val)
+	default:
+		return nil, fmt.Errorf("invalid JSON value for bare item: %v", v)
+	}
+}
+
+func numberFromJSON(n json.Number) (BareItem, error) {
+	s := string(n)
+	if strings.ContainsAny(s, "eE") {
+		return nil, fmt.Errorf("numbers with an exponent are ambiguous: %s", s)
+	}
+	dot := strings.IndexByte(s, '.')
+	if dot == -1 {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer: %s", s)
+		}
+		return &bareItem{val: v}, nil
+	}
+	intPart, fracPart := s[:dot], s[dot+1:]
+	neg := strings.HasPrefix(intPart, "-")
+	if neg {
+		intPart = intPart[1:]
+	}
+	if len(intPart) > decimalIntDigits || len(fracPart) > decimalFracDigits {
+		return nil, fmt.Errorf("decimal out of range: %s", s)
+	}
+	milli, err := roundToMilli(intPart, fracPart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid decimal: %s", s)
+	}
+	if neg {
+		milli = -milli
+	}
+	return &bareItem{val: NewDecimal(milli)}, nil
+}
+
+func typedValueFromJSON(m map[string]interface{}) (BareItem, error) {
+	typ, ok := m["__type"].(string)
+	if !ok || len(m) != 2 {
+		return nil, errors.New(`typed value must be a JSON object with "__type" and "value"`)
+	}
+	value, ok := m["value"]
+	if !ok {
+		return nil, errors.New(`typed value must have "value"`)
+	}
+	switch typ {
+	case jsonTypeToken:
+		s, ok := value.(string)
+		if !ok || !ValidToken(s) {
+			return nil, fmt.Errorf("invalid token: %v", value)
+		}
+		return &bareItem{val: Token(s)}, nil
+	case jsonTypeBinary:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid binary: %v", value)
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 in binary: %s", s)
+		}
+		return &bareItem{val: b}, nil
+	case jsonTypeDate:
+		n, ok := value.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("invalid date: %v", value)
+		}
+		sec, err := strconv.ParseInt(string(n), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("date must be integer seconds: %s", n)
+		}
+		return &bareItem{val: time.Unix(sec, 0).UTC()}, nil
+	case jsonTypeDisplayString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid display string: %v", value)
+		}
+		return &bareItem{val: DisplayString(s)}, nil
+	default:
+		return nil, fmt.Errorf("unknown type of typed value: %s", typ)
+	}
+}
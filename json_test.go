@@ -0,0 +1,129 @@
+package stheader_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	dict, err := stheader.NewParser(`a=(foo "bar");p=1, b=*aGk=*;q=1.5, c=?0, d=@1, e=%"f%c3%bc"`).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[["a",[[[{"__type":"token","value":"foo"},[]],["bar",[]]],[["p",1]]]],` +
+		`["b",[{"__type":"binary","value":"aGk="},[["q",1.5]]]],` +
+		`["c",[false,[]]],` +
+		`["d",[{"__type":"date","value":1},[]]],` +
+		`["e",[{"__type":"displaystring","value":"fü"},[]]]]`
+	if string(got) != want {
+		t.Errorf("dictionary mismatch,\n got=%s\nwant=%s", got, want)
+	}
+
+	list, err := stheader.NewParser(`1, 2.0;x="y"`).ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = json.Marshal(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[[1,[]],[2.0,[["x","y"]]]]`; string(got) != want {
+		t.Errorf("list mismatch, got=%s, want=%s", got, want)
+	}
+
+	got, err = json.Marshal(list[1].AsItem())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[2.0,[["x","y"]]]`; string(got) != want {
+		t.Errorf("item mismatch, got=%s, want=%s", got, want)
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(got, &decoded); err != nil {
+		t.Fatalf("marshaled JSON should be valid: %s", err)
+	}
+}
+
+func TestMarshalJSONInvalid(t *testing.T) {
+	list := stheader.List{stheader.NewMember(stheader.NewItem(nil, nil))}
+	if _, err := json.Marshal(list); err == nil {
+		t.Error("should fail for an item without a bare item")
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		input      string
+		headerType string
+	}{
+		{input: `a=(foo "bar");p=1, b=*aGk=*;q=1.5, c=?0, d=@1, e=%"f%c3%bc"`, headerType: "dictionary"},
+		{input: `1, 2.0;x="y", foo;bar, (1.25 -3)`, headerType: "list"},
+		{input: `"foo";a=1;b=1.0;c=tok`, headerType: "item"},
+	}
+	for _, c := range testCases {
+		v, err := parse(c.headerType, c.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got interface{}
+		switch c.headerType {
+		case "dictionary":
+			got, err = stheader.UnmarshalDictionaryJSON(data)
+		case "list":
+			got, err = stheader.UnmarshalListJSON(data)
+		case "item":
+			got, err = stheader.UnmarshalItemJSON(data)
+		}
+		if err != nil {
+			t.Fatalf("unmarshal %s: %s", data, err)
+		}
+		if !stheader.Equal(got, v) {
+			t.Errorf("unmarshaled value mismatch for %s", data)
+		}
+		s, err := stheader.Serialize(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != c.input {
+			t.Errorf("serialized mismatch, got=%q, want=%q", s, c.input)
+		}
+	}
+}
+
+func TestUnmarshalJSONError(t *testing.T) {
+	testCases := []string{
+		`{}`,
+		`[1, []]`,
+		`[[1, 2]]`,
+		`[[1, []]] x`,
+		`[[1e3, []]]`,
+		`[[1.2345, []]]`,
+		`[[{"__type": "token", "value": "1a"}, []]]`,
+		`[[{"__type": "token", "value": "a", "x": 1}, []]]`,
+		`[[{"__type": "unknown", "value": "a"}, []]]`,
+		`[[{"__type": "binary", "value": "!!"}, []]]`,
+		`[[{"__type": "date", "value": 1.5}, []]]`,
+		`[[1, [["A", 1]]]]`,
+		`[[1, [["a", 1], ["a", 2]]]]`,
+		`[[null, []]]`,
+	}
+	for _, input := range testCases {
+		if _, err := stheader.UnmarshalListJSON([]byte(input)); err == nil {
+			t.Errorf("should fail for %s", input)
+		}
+	}
+	if _, err := stheader.UnmarshalDictionaryJSON([]byte(`[["a", [1, []]], ["a", [2, []]]]`)); err == nil {
+		t.Error("should fail for a duplicate dictionary key")
+	}
+}
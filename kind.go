@@ -0,0 +1,74 @@
+package stheader
+
+import "strconv"
+
+// Kind is the unified enumerated type of the values of this package.
+// It is useful for code which processes values of any structure, such
+// as Walk, to switch on a single type.
+type Kind int
+
+const (
+	// KindInvalid is the kind of values not of this package.
+	KindInvalid Kind = iota
+	// KindDictionary is the kind of Dictionary.
+	KindDictionary
+	// KindList is the kind of List.
+	KindList
+	// KindMember is the kind of Member. Use Member.Type to know
+	// whether it is an Item or an InnerList.
+	KindMember
+	// KindInnerList is the kind of InnerList.
+	KindInnerList
+	// KindItem is the kind of Item.
+	KindItem
+	// KindParameters is the kind of Parameters.
+	KindParameters
+	// KindBareItem is the kind of BareItem.
+	KindBareItem
+)
+
+// KindOf returns the Kind of value.
+// It returns KindInvalid if value is nil or of another type.
+func KindOf(value interface{}) Kind {
+	switch value.(type) {
+	case Dictionary:
+		return KindDictionary
+	case List:
+		return KindList
+	case Member:
+		return KindMember
+	case InnerList:
+		return KindInnerList
+	case Item:
+		return KindItem
+	case Parameters:
+		return KindParameters
+	case BareItem:
+		return KindBareItem
+	default:
+		return KindInvalid
+	}
+}
+
+// String returns the string representation for Kind.
+// It returns "Kind(n)" for KindInvalid and unknown values.
+func (k Kind) String() string {
+	switch k {
+	case KindDictionary:
+		return "dictionary"
+	case KindList:
+		return "list"
+	case KindMember:
+		return "member"
+	case KindInnerList:
+		return "innerList"
+	case KindItem:
+		return "item"
+	case KindParameters:
+		return "parameters"
+	case KindBareItem:
+		return "bareItem"
+	default:
+		return "Kind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
@@ -0,0 +1,44 @@
+package stheader_test
+
+import (
+	"testing"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestKindOf(t *testing.T) {
+	dict, err := stheader.NewParser(`a=(1 2);x, b="s"`).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := dict.Load("a")
+	b, _ := dict.Load("b")
+	testCases := []struct {
+		value interface{}
+		want  stheader.Kind
+	}{
+		{value: dict, want: stheader.KindDictionary},
+		{value: stheader.List{a, b}, want: stheader.KindList},
+		{value: a, want: stheader.KindMember},
+		{value: a.AsInnerList(), want: stheader.KindInnerList},
+		{value: b.AsItem(), want: stheader.KindItem},
+		{value: a.AsInnerList().Parameters(), want: stheader.KindParameters},
+		{value: b.AsItem().BareItem(), want: stheader.KindBareItem},
+		{value: nil, want: stheader.KindInvalid},
+		{value: "s", want: stheader.KindInvalid},
+	}
+	for _, c := range testCases {
+		if got := stheader.KindOf(c.value); got != c.want {
+			t.Errorf("result mismatch for %T, got=%v, want=%v", c.value, got, c.want)
+		}
+	}
+}
+
+func TestKindString(t *testing.T) {
+	if got, want := stheader.KindInnerList.String(), "innerList"; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+	if got, want := stheader.Kind(100).String(), "Kind(100)"; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+}
@@ -0,0 +1,371 @@
+package stheader
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Marshaler is the interface implemented by types that can convert
+// themselves into a structured header value.
+//
+// MarshalStructuredHeader must return a Dictionary, a List or an Item,
+// which is serialized by Serialize in place of the receiver.
+type Marshaler interface {
+	MarshalStructuredHeader() (interface{}, error)
+}
+
+// Unmarshaler is the interface implemented by types that can set
+// themselves from a structured header value.
+//
+// UnmarshalStructuredHeader receives a parsed Dictionary, List or
+// Item and must return an error if the value does not fit the
+// receiver. The value must be copied if it is retained after
+// returning.
+type Unmarshaler interface {
+	UnmarshalStructuredHeader(value interface{}) error
+}
+
+// Marshal returns the serialized Dictionary built from the struct v
+// or a pointer to it.
+//
+// Each exported field with a tag like `stheader:"key"` becomes a
+// member of the dictionary. Fields without the tag or with the tag
+// "-" are skipped. The field types are mapped to bare items as below:
+//
+//	string                          String
+//	[]byte                          Byte Sequence
+//	bool                            Boolean
+//	int, int8, ..., uint64          Integer
+//	float32, float64                Decimal
+//	Token                           Token
+//	Decimal                         Decimal
+//	time.Time                       Date
+//	DisplayString                   Display String
+//
+// A field of other struct types becomes an Item with parameters.
+// The field of the struct tagged `stheader:",value"` is the bare item
+// and the other tagged fields are the parameters.
+//
+//	type Priority struct {
+//		Urgency struct {
+//			Value       int64 `stheader:",value"`
+//			Incremental bool  `stheader:"i"`
+//		} `stheader:"u"`
+//	}
+func Marshal(v interface{}) (string, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return "", errors.New("marshal nil pointer")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return "", fmt.Errorf("marshal non-struct type: %T", v)
+	}
+	fields, err := structFields(rv.Type())
+	if err != nil {
+		return "", err
+	}
+	dict := &dictionary{}
+	for _, f := range fields {
+		if f.value {
+			return "", fmt.Errorf("field %s: value option is only allowed in a member struct", f.goName)
+		}
+		it, err := marshalItem(rv.Field(f.index))
+		if err != nil {
+			return "", fmt.Errorf("field %s: %w", f.goName, err)
+		}
+		dict.Store(f.key, &member{val: it})
+	}
+	return Serialize(dict)
+}
+
+// structField is a field of a struct with the stheader tag.
+type structField struct {
+	index    int
+	goName   string
+	key      string
+	value    bool
+	required bool
+}
+
+// structFields returns the exported fields of t with the stheader tag.
+func structFields(t reflect.Type) ([]structField, error) {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("stheader")
+		if sf.PkgPath != "" || !ok || tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		f := structField{index: i, goName: sf.Name, key: parts[0]}
+		for _, opt := range parts[1:] {
+			switch opt {
+			case "value":
+				f.value = true
+			case "required":
+				f.required = true
+			default:
+				return nil, fmt.Errorf("field %s: unknown tag option: %q", sf.Name, opt)
+			}
+		}
+		if f.value {
+			if f.key != "" {
+				return nil, fmt.Errorf("field %s: key must be empty with value option", sf.Name)
+			}
+		} else if !ValidKey(f.key) {
+			return nil, fmt.Errorf("field %s: invalid key: %q", sf.Name, f.key)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+var (
+	tokenReflectType         = reflect.TypeOf(Token(""))
+	displayStringReflectType = reflect.TypeOf(DisplayString(""))
+	decimalReflectType       = reflect.TypeOf(Decimal{})
+	timeReflectType          = reflect.TypeOf(time.Time{})
+)
+
+// isMemberStruct reports whether t is a struct type for an Item with
+// parameters, not a struct type for a bare item.
+func isMemberStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != decimalReflectType && t != timeReflectType
+}
+
+func marshalItem(v reflect.Value) (Item, error) {
+	if !isMemberStruct(v.Type()) {
+		bi, err := marshalBareItem(v)
+		if err != nil {
+			return nil, err
+		}
+		return &item{bareItem: bi}, nil
+	}
+
+	fields, err := structFields(v.Type())
+	if err != nil {
+		return nil, err
+	}
+	var bi BareItem
+	params := &parameters{}
+	for _, f := range fields {
+		pbi, err := marshalBareItem(v.Field(f.index))
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.goName, err)
+		}
+		if f.value {
+			bi = pbi
+		} else {
+			params.Store(f.key, pbi)
+		}
+	}
+	if bi == nil {
+		return nil, fmt.Errorf("no field with value option in %s", v.Type())
+	}
+	return &item{bareItem: bi, params: params}, nil
+}
+
+func marshalBareItem(v reflect.Value) (BareItem, error) {
+	switch v.Type() {
+	case tokenReflectType:
+		return &bareItem{val: Token(v.String())}, nil
+	case displayStringReflectType:
+		return &bareItem{val: DisplayString(v.String())}, nil
+	case decimalReflectType, timeReflectType:
+		return &bareItem{val: v.Interface()}, nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return &bareItem{val: v.String()}, nil
+	case reflect.Bool:
+		return &bareItem{val: v.Bool()}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &bareItem{val: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("uint value overflows int64: %d", v.Uint())
+		}
+		return &bareItem{val: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &bareItem{val: v.Float()}, nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return &bareItem{val: v.Bytes()}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported type: %s", v.Type())
+}
+
+// UnmarshalTypeError describes a bare item which cannot be stored
+// in a field of a Go type.
+type UnmarshalTypeError struct {
+	// Key is the dictionary key, followed by ";" and the parameter
+	// name for a parameter.
+	Key string
+	// Value is the type of the bare item.
+	Value ItemType
+	// Type is the type of the field.
+	Type reflect.Type
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	return fmt.Sprintf("cannot unmarshal %s into field of type %s for key %q", e.Value, e.Type, e.Key)
+}
+
+// Unmarshal parses input as a Dictionary and stores the members in
+// the struct pointed to by v.
+//
+// The fields are mapped with the same tags and types as Marshal.
+// Integer values are also accepted for float fields, and a parameter
+// without a value is true for a bool field. If the type of a member
+// does not fit the field, Unmarshal returns an *UnmarshalTypeError.
+//
+// Fields for missing keys are left unchanged unless the tag has the
+// "required" option like `stheader:"key,required"`, in which case
+// Unmarshal returns an error.
+//
+// If v implements Unmarshaler, its UnmarshalStructuredHeader method
+// is called with the parsed Dictionary instead.
+func Unmarshal(input string, v interface{}) error {
+	dict, err := NewParser(input).ParseDictionary()
+	if err != nil {
+		return err
+	}
+	if u, ok := v.(Unmarshaler); ok {
+		return u.UnmarshalStructuredHeader(dict)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("unmarshal requires a non-nil pointer to struct, got %T", v)
+	}
+	rv = rv.Elem()
+	fields, err := structFields(rv.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if f.value {
+			return fmt.Errorf("field %s: value option is only allowed in a member struct", f.goName)
+		}
+		m, ok := dict.Load(f.key)
+		if !ok {
+			if f.required {
+				return fmt.Errorf("missing required key %q", f.key)
+			}
+			continue
+		}
+		if m.Type() != MemberTypeItem {
+			return fmt.Errorf("cannot unmarshal inner list into field of type %s for key %q", rv.Field(f.index).Type(), f.key)
+		}
+		if err := unmarshalItem(f.key, m.AsItem(), rv.Field(f.index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unmarshalItem(key string, it Item, v reflect.Value) error {
+	if !isMemberStruct(v.Type()) {
+		return unmarshalBareItem(key, it.BareItem(), v)
+	}
+
+	fields, err := structFields(v.Type())
+	if err != nil {
+		return err
+	}
+	params := it.Parameters()
+	for _, f := range fields {
+		if f.value {
+			if err := unmarshalBareItem(key, it.BareItem(), v.Field(f.index)); err != nil {
+				return err
+			}
+			continue
+		}
+		var bi BareItem
+		ok := false
+		if params != nil {
+			bi, ok = params.Load(f.key)
+		}
+		if !ok {
+			if f.required {
+				return fmt.Errorf("missing required parameter %q for key %q", f.key, key)
+			}
+			continue
+		}
+		if err := unmarshalBareItem(key+";"+f.key, bi, v.Field(f.index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unmarshalBareItem(key string, bi BareItem, v reflect.Value) error {
+	typeErr := &UnmarshalTypeError{Key: key, Value: bi.Type(), Type: v.Type()}
+	val := bareItemValue(bi)
+	switch v.Type() {
+	case tokenReflectType, displayStringReflectType, decimalReflectType, timeReflectType:
+		rval := reflect.ValueOf(val)
+		if rval.Type() != v.Type() {
+			return typeErr
+		}
+		v.Set(rval)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		s, ok := val.(string)
+		if !ok {
+			return typeErr
+		}
+		v.SetString(s)
+	case reflect.Bool:
+		b, ok := val.(bool)
+		if !ok {
+			return typeErr
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := val.(int64)
+		if !ok || v.OverflowInt(i) {
+			return typeErr
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, ok := val.(int64)
+		if !ok || i < 0 || v.OverflowUint(uint64(i)) {
+			return typeErr
+		}
+		v.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		switch n := val.(type) {
+		case int64:
+			v.SetFloat(float64(n))
+		case float64:
+			v.SetFloat(n)
+		case Decimal:
+			v.SetFloat(n.Float64())
+		default:
+			return typeErr
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("unsupported type: %s", v.Type())
+		}
+		b, ok := val.([]byte)
+		if !ok {
+			return typeErr
+		}
+		v.SetBytes(append([]byte(nil), b...))
+	default:
+		return fmt.Errorf("unsupported type: %s", v.Type())
+	}
+	return nil
+}
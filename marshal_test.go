@@ -0,0 +1,317 @@
+package stheader_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+// cacheControl is an example type implementing stheader.Marshaler
+// and stheader.Unmarshaler.
+type cacheControl struct {
+	MaxAge  int64
+	Private bool
+}
+
+func (c cacheControl) MarshalStructuredHeader() (interface{}, error) {
+	if c.MaxAge < 0 {
+		return nil, errors.New("negative max-age")
+	}
+	dict := stheader.NewDictionary()
+	dict.Store("max-age", stheader.NewMember(stheader.NewItem(stheader.NewBareItem(c.MaxAge), nil)))
+	if c.Private {
+		dict.Store("private", stheader.NewMember(stheader.NewItem(stheader.NewBareItem(true), nil)))
+	}
+	return dict, nil
+}
+
+func (c *cacheControl) UnmarshalStructuredHeader(value interface{}) error {
+	dict, ok := value.(stheader.Dictionary)
+	if !ok {
+		return errors.New("cache control must be a dictionary")
+	}
+	*c = cacheControl{}
+	if m, ok := dict.Load("max-age"); ok {
+		if m.Type() != stheader.MemberTypeItem {
+			return errors.New("max-age must be an item")
+		}
+		v, ok := m.AsItem().BareItem().IntValue()
+		if !ok {
+			return errors.New("max-age must be an integer")
+		}
+		c.MaxAge = v
+	}
+	if m, ok := dict.Load("private"); ok {
+		if m.Type() != stheader.MemberTypeItem {
+			return errors.New("private must be an item")
+		}
+		v, ok := m.AsItem().BareItem().BoolValue()
+		if !ok {
+			return errors.New("private must be a boolean")
+		}
+		c.Private = v
+	}
+	return nil
+}
+
+func TestSerializeMarshaler(t *testing.T) {
+	got, err := stheader.Serialize(cacheControl{MaxAge: 60, Private: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "max-age=60, private"; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+
+	if _, err := stheader.Serialize(cacheControl{MaxAge: -1}); err == nil {
+		t.Error("should return the error from MarshalStructuredHeader")
+	}
+}
+
+func TestUnmarshaler(t *testing.T) {
+	dict, err := stheader.NewParser("max-age=60, private=?1").ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c cacheControl
+	if err := c.UnmarshalStructuredHeader(dict); err != nil {
+		t.Fatal(err)
+	}
+	if want := (cacheControl{MaxAge: 60, Private: true}); c != want {
+		t.Errorf("result mismatch, got=%+v, want=%+v", c, want)
+	}
+
+	dict, err = stheader.NewParser(`max-age="60"`).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UnmarshalStructuredHeader(dict); err == nil {
+		t.Error("should fail for a string max-age")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	type urgency struct {
+		Value       int64 `stheader:",value"`
+		Incremental bool  `stheader:"i"`
+		Label       stheader.Token
+	}
+	type header struct {
+		Name     string           `stheader:"name"`
+		Data     []byte           `stheader:"data"`
+		Enabled  bool             `stheader:"enabled"`
+		Count    int              `stheader:"count"`
+		Ratio    float64          `stheader:"ratio"`
+		Kind     stheader.Token   `stheader:"kind"`
+		Urgency  urgency          `stheader:"u"`
+		Decimal  stheader.Decimal `stheader:"dec"`
+		Skipped  string           `stheader:"-"`
+		Untagged string
+		private  string
+	}
+	v := header{
+		Name:     "foo",
+		Data:     []byte("hi"),
+		Enabled:  true,
+		Count:    -3,
+		Ratio:    0.5,
+		Kind:     "text/html",
+		Urgency:  urgency{Value: 3, Incremental: true, Label: "x"},
+		Decimal:  stheader.NewDecimal(1250),
+		Skipped:  "skipped",
+		Untagged: "untagged",
+		private:  "private",
+	}
+	want := `name="foo", data=*aGk=*, enabled, count=-3, ratio=0.5, kind=text/html, u=3;i, dec=1.25`
+	for _, value := range []interface{}{v, &v} {
+		got, err := stheader.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("result mismatch,\ngot= %q\nwant=%q", got, want)
+		}
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	type noValue struct {
+		A int64 `stheader:"a"`
+	}
+	testCases := []struct {
+		name  string
+		value interface{}
+	}{
+		{name: "non-struct", value: 1},
+		{name: "nil pointer", value: (*cacheControl)(nil)},
+		{name: "invalid key", value: struct {
+			A int64 `stheader:"A"`
+		}{}},
+		{name: "unsupported type", value: struct {
+			A []int `stheader:"a"`
+		}{}},
+		{name: "unknown option", value: struct {
+			A int64 `stheader:"a,foo"`
+		}{}},
+		{name: "no value field", value: struct {
+			A noValue `stheader:"a"`
+		}{}},
+		{name: "invalid token", value: struct {
+			A stheader.Token `stheader:"a"`
+		}{A: "1a"}},
+	}
+	for _, c := range testCases {
+		if _, err := stheader.Marshal(c.value); err == nil {
+			t.Errorf("should fail for %s", c.name)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	type urgency struct {
+		Value       int64 `stheader:",value"`
+		Incremental bool  `stheader:"i"`
+	}
+	type header struct {
+		Name    string         `stheader:"name"`
+		Data    []byte         `stheader:"data"`
+		Enabled bool           `stheader:"enabled"`
+		Count   int8           `stheader:"count"`
+		Ratio   float64        `stheader:"ratio"`
+		Kind    stheader.Token `stheader:"kind"`
+		Urgency urgency        `stheader:"u"`
+		Missing string         `stheader:"missing"`
+	}
+	const input = `name="foo", data=*aGk=*, enabled, count=-3, ratio=0.5, kind=text/html, u=3;i, unknown=1`
+	v := header{Missing: "unchanged"}
+	if err := stheader.Unmarshal(input, &v); err != nil {
+		t.Fatal(err)
+	}
+	want := header{
+		Name:    "foo",
+		Data:    []byte("hi"),
+		Enabled: true,
+		Count:   -3,
+		Ratio:   0.5,
+		Kind:    "text/html",
+		Urgency: urgency{Value: 3, Incremental: true},
+		Missing: "unchanged",
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("result mismatch,\ngot= %+v\nwant=%+v", v, want)
+	}
+
+	var c cacheControl
+	if err := stheader.Unmarshal("max-age=60, private=?1", &c); err != nil {
+		t.Fatal(err)
+	}
+	if want := (cacheControl{MaxAge: 60, Private: true}); c != want {
+		t.Errorf("Unmarshaler result mismatch, got=%+v, want=%+v", c, want)
+	}
+}
+
+func TestUnmarshalTypeMismatch(t *testing.T) {
+	type header struct {
+		Count int8           `stheader:"count"`
+		Kind  stheader.Token `stheader:"kind"`
+		Flag  struct {
+			Value bool   `stheader:",value"`
+			Name  string `stheader:"n"`
+		} `stheader:"flag"`
+	}
+	testCases := []struct {
+		input   string
+		wantKey string
+	}{
+		{input: `count="3"`, wantKey: "count"},
+		{input: `count=1000`, wantKey: "count"},
+		{input: `kind="text"`, wantKey: "kind"},
+		{input: `flag=1`, wantKey: "flag"},
+		{input: `flag=?1;n=1`, wantKey: "flag;n"},
+	}
+	for _, c := range testCases {
+		var v header
+		err := stheader.Unmarshal(c.input, &v)
+		var terr *stheader.UnmarshalTypeError
+		if !errors.As(err, &terr) {
+			t.Errorf("should fail with UnmarshalTypeError for %q, got=%v", c.input, err)
+			continue
+		}
+		if terr.Key != c.wantKey {
+			t.Errorf("key mismatch for %q, got=%q, want=%q", c.input, terr.Key, c.wantKey)
+		}
+	}
+
+	var v header
+	if err := stheader.Unmarshal(`count=(1 2)`, &v); err == nil {
+		t.Error("should fail for an inner list")
+	}
+	if err := stheader.Unmarshal(`count=1`, v); err == nil {
+		t.Error("should fail for a non-pointer")
+	}
+}
+
+func TestUnmarshalNilParameter(t *testing.T) {
+	type urgency struct {
+		Value       int64 `stheader:",value"`
+		Incremental bool  `stheader:"i"`
+	}
+	type header struct {
+		Urgency urgency `stheader:"u"`
+	}
+
+	params := stheader.NewParameters()
+	params.Store("i", nil)
+	dict := stheader.NewDictionary()
+	dict.Store("u", stheader.NewMember(stheader.NewItem(stheader.NewBareItem(int64(3)), params)))
+	input, err := stheader.Serialize(dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "u=3;i"; input != want {
+		t.Errorf("serialized mismatch, got=%q, want=%q", input, want)
+	}
+	var v header
+	if err := stheader.Unmarshal(input, &v); err != nil {
+		t.Fatal(err)
+	}
+	if want := (header{Urgency: urgency{Value: 3, Incremental: true}}); v != want {
+		t.Errorf("result mismatch, got=%+v, want=%+v", v, want)
+	}
+
+	it, err := stheader.UnmarshalItemJSON([]byte(`[3, [["i", null]]]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bi, ok := it.Parameters().Load("i"); !ok || !bi.AsBool() {
+		t.Errorf("null parameter should be stored as true, got=%v", bi)
+	}
+}
+
+func TestUnmarshalRequired(t *testing.T) {
+	type header struct {
+		A int64 `stheader:"a,required"`
+		B struct {
+			Value int64 `stheader:",value"`
+			P     int64 `stheader:"p,required"`
+		} `stheader:"b"`
+	}
+	var v header
+	if err := stheader.Unmarshal(`a=1, b=2;p=3`, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A != 1 || v.B.Value != 2 || v.B.P != 3 {
+		t.Errorf("result mismatch, got=%+v", v)
+	}
+	if err := stheader.Unmarshal(`b=2;p=3`, &v); err == nil {
+		t.Error("should fail for a missing required key")
+	}
+	if err := stheader.Unmarshal(`a=1, b=2`, &v); err == nil {
+		t.Error("should fail for a missing required parameter")
+	}
+	if err := stheader.Unmarshal(`a=1`, &v); err != nil {
+		t.Errorf("should not fail for a missing optional member with required parameter: %s", err)
+	}
+}
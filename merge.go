@@ -0,0 +1,60 @@
+package stheader
+
+import "fmt"
+
+// MergePolicy specifies how MergeDictionaries resolves a key present
+// in both dictionaries.
+type MergePolicy int
+
+const (
+	// MergeKeepBase keeps the member of the base dictionary.
+	MergeKeepBase MergePolicy = iota
+	// MergeKeepOverlay replaces the member with the one of the overlay
+	// dictionary, keeping the position of the key in the base.
+	MergeKeepOverlay
+	// MergeErrorOnConflict makes MergeDictionaries return an error.
+	MergeErrorOnConflict
+)
+
+// MergeDictionaries returns a new Dictionary which has the members of
+// base followed by the members of overlay whose keys are not in base.
+// Keys present in both are resolved with policy.
+//
+// The members are deep copied, so modifying the result never affects
+// base and overlay. Nil dictionaries are treated as empty.
+func MergeDictionaries(base, overlay Dictionary, policy MergePolicy) (Dictionary, error) {
+	ret := &dictionary{}
+	if base != nil {
+		base.Range(func(name string, value Member) bool {
+			ret.items = append(ret.items, dictItem{name: name, value: CloneMember(value)})
+			return true
+		})
+	}
+	if overlay == nil {
+		return ret, nil
+	}
+	var err error
+	overlay.Range(func(name string, value Member) bool {
+		i := ret.index(name)
+		if i == -1 {
+			ret.items = append(ret.items, dictItem{name: name, value: CloneMember(value)})
+			return true
+		}
+		switch policy {
+		case MergeKeepBase:
+		case MergeKeepOverlay:
+			ret.items[i].value = CloneMember(value)
+		case MergeErrorOnConflict:
+			err = fmt.Errorf("conflicting key: %q", name)
+			return false
+		default:
+			err = fmt.Errorf("invalid merge policy: %d", policy)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
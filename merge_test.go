@@ -0,0 +1,72 @@
+package stheader_test
+
+import (
+	"testing"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestMergeDictionaries(t *testing.T) {
+	base, err := stheader.NewParser(`a=1, b=*aGk=*, c=3`).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlay, err := stheader.NewParser(`d=4, b=*aG8=*, e`).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		policy stheader.MergePolicy
+		want   string
+	}{
+		{policy: stheader.MergeKeepBase, want: `a=1, b=*aGk=*, c=3, d=4, e`},
+		{policy: stheader.MergeKeepOverlay, want: `a=1, b=*aG8=*, c=3, d=4, e`},
+	}
+	for _, c := range testCases {
+		merged, err := stheader.MergeDictionaries(base, overlay, c.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := stheader.Serialize(merged)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("result mismatch for policy %d, got=%q, want=%q", c.policy, got, c.want)
+		}
+
+		// Byte sequences must not be shared with the sources.
+		m, _ := merged.Load("b")
+		m.AsItem().BareItem().AsByteSeq()[0] = 'X'
+	}
+	if got, want := mustSerialize(t, base), `a=1, b=*aGk=*, c=3`; got != want {
+		t.Errorf("base modified, got=%q, want=%q", got, want)
+	}
+	if got, want := mustSerialize(t, overlay), `d=4, b=*aG8=*, e`; got != want {
+		t.Errorf("overlay modified, got=%q, want=%q", got, want)
+	}
+
+	if _, err := stheader.MergeDictionaries(base, overlay, stheader.MergeErrorOnConflict); err == nil {
+		t.Error("should fail for a conflicting key")
+	}
+	other, err := stheader.NewParser(`x=1`).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := stheader.MergeDictionaries(base, other, stheader.MergeErrorOnConflict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := mustSerialize(t, merged), `a=1, b=*aGk=*, c=3, x=1`; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+}
+
+func mustSerialize(t *testing.T, v interface{}) string {
+	t.Helper()
+	s, err := stheader.Serialize(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
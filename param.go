@@ -0,0 +1,28 @@
+package stheader
+
+// ParamValue returns the value of the parameter name in p converted
+// to T, and true. It returns the zero value and false if p is nil,
+// the parameter is absent, or the value type is not T.
+//
+// T is one of string, []byte, bool, int64, float64, Token, Decimal,
+// time.Time and DisplayString. For float64, a "Decimal" value is also
+// accepted and converted since numbers with a fractional part are
+// parsed as Decimal.
+func ParamValue[T any](p Parameters, name string) (T, bool) {
+	var zero T
+	if p == nil {
+		return zero, false
+	}
+	bi, ok := p.Load(name)
+	if !ok {
+		return zero, false
+	}
+	val := bareItemValue(bi)
+	if d, ok := val.(Decimal); ok {
+		if _, ok := interface{}(zero).(float64); ok {
+			val = d.Float64()
+		}
+	}
+	v, ok := val.(T)
+	return v, ok
+}
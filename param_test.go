@@ -0,0 +1,50 @@
+package stheader_test
+
+import (
+	"bytes"
+	"testing"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestParamValue(t *testing.T) {
+	item, err := stheader.NewParser(`1;s="foo";i=2;f=0.5;b;t=bar;bs=*aGk=*`).ParseItem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := item.Parameters()
+
+	if v, ok := stheader.ParamValue[string](params, "s"); !ok || v != "foo" {
+		t.Errorf("string mismatch, got=%q, %v", v, ok)
+	}
+	if v, ok := stheader.ParamValue[int64](params, "i"); !ok || v != 2 {
+		t.Errorf("int64 mismatch, got=%d, %v", v, ok)
+	}
+	if v, ok := stheader.ParamValue[float64](params, "f"); !ok || v != 0.5 {
+		t.Errorf("float64 mismatch, got=%v, %v", v, ok)
+	}
+	if v, ok := stheader.ParamValue[stheader.Decimal](params, "f"); !ok || v != stheader.NewDecimal(500) {
+		t.Errorf("Decimal mismatch, got=%v, %v", v, ok)
+	}
+	if v, ok := stheader.ParamValue[stheader.Token](params, "t"); !ok || v != "bar" {
+		t.Errorf("Token mismatch, got=%q, %v", v, ok)
+	}
+	if v, ok := stheader.ParamValue[[]byte](params, "bs"); !ok || !bytes.Equal(v, []byte("hi")) {
+		t.Errorf("[]byte mismatch, got=%q, %v", v, ok)
+	}
+
+	params.Store("b", stheader.NewBareItem(true))
+	if v, ok := stheader.ParamValue[bool](params, "b"); !ok || !v {
+		t.Errorf("bool mismatch, got=%v, %v", v, ok)
+	}
+
+	if v, ok := stheader.ParamValue[string](params, "i"); ok || v != "" {
+		t.Errorf("type mismatch should return zero and false, got=%q, %v", v, ok)
+	}
+	if v, ok := stheader.ParamValue[int64](params, "missing"); ok || v != 0 {
+		t.Errorf("missing parameter should return zero and false, got=%d, %v", v, ok)
+	}
+	if _, ok := stheader.ParamValue[int64](nil, "i"); ok {
+		t.Error("nil parameters should return false")
+	}
+}
@@ -2,39 +2,225 @@ package stheader
 
 import (
 	"bytes"
-	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
-	"regexp"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+// Errors which ParseError wraps to classify failures.
+// Use errors.Is to check them.
+var (
+	ErrUnexpectedEOF    = errors.New("stheader: unexpected end of input")
+	ErrDuplicateKey     = errors.New("stheader: duplicate key")
+	ErrInvalidCharacter = errors.New("stheader: invalid character")
+	ErrTooLarge         = errors.New("stheader: input too large")
+	ErrTooComplex       = errors.New("stheader: input too complex")
+)
+
+// ParseError is the error type returned by Parser.
 type ParseError struct {
-	msg string
-	pos int
+	msg  string
+	pos  int
+	kind error
+	err  error
 }
 
 func (e *ParseError) Error() string {
 	return e.msg
 }
 
+// Pos returns the byte offset in the input where the error occurred.
 func (e *ParseError) Pos() int {
 	return e.pos
 }
 
+// Is reports whether target is the sentinel error which classifies
+// the error, one of ErrUnexpectedEOF, ErrDuplicateKey,
+// ErrInvalidCharacter, ErrTooLarge, ErrTooComplex and ErrIncomplete.
+func (e *ParseError) Is(target error) bool {
+	return e.kind != nil && target == e.kind
+}
+
+// Unwrap returns the underlying error. For errors of numbers, it is
+// a *strconv.NumError whose Err is strconv.ErrSyntax for malformed
+// digits or strconv.ErrRange for out-of-range values. For errors of
+// Parser.ByteSeqHook, it is the error returned by the hook. For other
+// errors, it returns the classifying sentinel error, or nil.
+func (e *ParseError) Unwrap() error {
+	if e.err != nil {
+		return e.err
+	}
+	return e.kind
+}
+
+// parseErrorContextWidth is the maximum number of bytes shown
+// before and after the error position in ParseError.Context.
+const parseErrorContextWidth = 20
+
+// Context returns the part of input around the error position with
+// a second line having a caret which points at the position.
+// input must be the input given to the Parser.
+// Bytes which are not printable ASCII characters are shown as '.'
+// so that the caret stays aligned.
+func (e *ParseError) Context(input string) string {
+	pos := e.pos
+	if pos < 0 {
+		pos = 0
+	} else if pos > len(input) {
+		pos = len(input)
+	}
+	start, prefix := pos-parseErrorContextWidth, "..."
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	end, suffix := pos+parseErrorContextWidth, "..."
+	if end >= len(input) {
+		end, suffix = len(input), ""
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	for i := start; i < end; i++ {
+		c := input[i]
+		if c < ' ' || c > '~' {
+			c = '.'
+		}
+		b.WriteByte(c)
+	}
+	b.WriteString(suffix)
+	b.WriteByte('\n')
+	b.WriteString(strings.Repeat(" ", len(prefix)+pos-start))
+	b.WriteByte('^')
+	return b.String()
+}
+
+// Parser parses a HTTP header value into Dictionary, List or Item.
 type Parser struct {
+	// RFC8941 makes the parser recognize "Byte Sequence" values
+	// delimited by colons as defined in RFC 8941 instead of
+	// asterisks of draft-ietf-httpbis-header-structure-14.
+	RFC8941 bool
+
+	// IntegerDigitLimit is the maximum number of digits of "Integer"
+	// values. Zero means the default limit of 15 digits.
+	IntegerDigitLimit int
+
+	// Strict makes the parser reject whitespace which the
+	// specification does not allow, that is, a horizontal tab
+	// after ";" of parameters, inside inner lists, or before or
+	// after the whole input. A horizontal tab around "," of List
+	// and Dictionary is allowed in either mode. Strict does not
+	// reject multiple spaces between items of inner lists, which
+	// are not canonical but allowed by the grammar.
+	Strict bool
+
+	// RecordRaw makes the parser record the source text of each
+	// member of List and Dictionary and the top-level Item, which
+	// is returned by their RawBytes methods.
+	RecordRaw bool
+
+	// RejectLeadingZeros makes the parser reject numbers whose
+	// integer part has superfluous leading zeros such as "007".
+	// "0", "-0" and "0.5" are still accepted.
+	RejectLeadingZeros bool
+
+	// RoundFractionalDigits makes the parser accept "Decimal" values
+	// with more than three fractional digits and round them to three
+	// digits with round-half-to-even, which is the same rule as the
+	// serializer uses for float64 values. By default such values are
+	// rejected as RFC 8941 requires.
+	RoundFractionalDigits bool
+
+	// LenientKeys makes the parser accept keys of dictionaries and
+	// parameters containing uppercase letters such as "Foo", which
+	// some senders emit. Such keys are lowercased, so the parsed
+	// values are still canonical.
+	LenientKeys bool
+
+	// ByteSeqEncoding is the encoding of "Byte Sequence" values.
+	// The default is base64 as the specification defines.
+	ByteSeqEncoding ByteSeqEncoding
+
+	// ByteSeqHook is called with each decoded "Byte Sequence" value,
+	// which the hook may modify. The returned slice is stored as the
+	// value. If it returns an error, parsing fails with a ParseError
+	// wrapping the error. Nil means the decoded value is stored as is.
+	ByteSeqHook func([]byte) ([]byte, error)
+
+	// MaxInputLen is the maximum length of input in bytes. Longer
+	// input is rejected before parsing. Zero means no limit.
+	MaxInputLen int
+
+	// MaxListMembers is the maximum number of members of List.
+	// Zero means no limit.
+	MaxListMembers int
+
+	// MaxDictEntries is the maximum number of members of Dictionary.
+	// Zero means no limit.
+	MaxDictEntries int
+
+	// MaxByteSeqLen is the maximum decoded length in bytes of each
+	// "Byte Sequence" value. It is checked with the length computed
+	// from the encoded value before decoding. Zero means no limit.
+	MaxByteSeqLen int
+
+	// MaxInnerListItems is the maximum number of items of each
+	// "Inner List". Zero means the default limit of 1024 items, which
+	// is well above 256 items that RFC 8941 requires parsers to
+	// support. A negative value means no limit.
+	MaxInnerListItems int
+
 	input []byte
 	pos   int
 	debug bool
+
+	// prefix makes parseList and parseDictionary stop at trailing
+	// data instead of returning an error.
+	prefix bool
+
+	// decodeBuf is the buffer reused for decoding "Byte Sequence"
+	// values. Its content is invalidated by the next decoding.
+	decodeBuf []byte
 }
 
+// NewParser creates a new Parser for input.
 func NewParser(input string) *Parser {
-	p := &Parser{input: []byte(input)}
-	p.skipOWS()
+	return NewParserBytes([]byte(input))
+}
+
+// NewParserBytes creates a new Parser for input without copying it.
+// The caller must not modify input while parsing.
+// Values returned by the parser do not share memory with input.
+func NewParserBytes(input []byte) *Parser {
+	p := &Parser{input: input}
+	p.skipSP()
 	return p
 }
 
+// Reset makes the parser parse input, keeping the configuration
+// fields such as RFC8941. This allows reusing a Parser for many
+// header values. Values returned by prior parses remain valid since
+// they never share memory with the parser.
+func (p *Parser) Reset(input string) {
+	p.ResetBytes([]byte(input))
+}
+
+// ResetBytes is like Reset but takes input without copying it.
+// The caller must not modify input while parsing.
+func (p *Parser) ResetBytes(input []byte) {
+	p.input = input
+	p.pos = 0
+	p.skipSP()
+}
+
 func (p *Parser) ParseDictionary() (Dictionary, error) {
+	if err := p.begin(); err != nil {
+		return nil, err
+	}
 	dict, err := p.parseDictionary()
 	if err != nil {
 		return nil, err
@@ -46,6 +232,9 @@ func (p *Parser) ParseDictionary() (Dictionary, error) {
 }
 
 func (p *Parser) ParseList() (List, error) {
+	if err := p.begin(); err != nil {
+		return nil, err
+	}
 	dict, err := p.parseList()
 	if err != nil {
 		return nil, err
@@ -57,37 +246,182 @@ func (p *Parser) ParseList() (List, error) {
 }
 
 func (p *Parser) ParseItem() (Item, error) {
+	if err := p.begin(); err != nil {
+		return nil, err
+	}
+	start := p.pos
 	dict, err := p.parseItem()
 	if err != nil {
 		return nil, err
 	}
+	if p.RecordRaw {
+		dict.(*item).raw = p.raw(start)
+	}
 	if err := p.end(); err != nil {
 		return nil, err
 	}
 	return dict, nil
 }
 
+// ParseBareItem parses a single bare item without parameters, such as
+// a parameter value taken from another context.
+func (p *Parser) ParseBareItem() (BareItem, error) {
+	if err := p.begin(); err != nil {
+		return nil, err
+	}
+	bi, err := p.parseBareItem("item")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+	return bi, nil
+}
+
+// ParseParameters parses standalone parameters such as ";a=1;b=2".
+// It returns empty Parameters for empty input.
+func (p *Parser) ParseParameters() (Parameters, error) {
+	if err := p.begin(); err != nil {
+		return nil, err
+	}
+	params, err := p.parseParameters()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// ParseDictionaryPrefix is like ParseDictionary but it does not return
+// an error for trailing data after a member where a comma or the end
+// of input is expected. It parses as many members as possible and
+// returns the remaining input after the last member and whitespace.
+// This is a non-strict mode for embedding a Dictionary in a larger
+// grammar. Invalid members are still reported as errors.
+func (p *Parser) ParseDictionaryPrefix() (Dictionary, string, error) {
+	if err := p.begin(); err != nil {
+		return nil, "", err
+	}
+	p.prefix = true
+	defer func() { p.prefix = false }()
+	dict, err := p.parseDictionary()
+	if err != nil {
+		return nil, "", err
+	}
+	return dict, p.Remaining(), nil
+}
+
+// ParseListPrefix is like ParseList but it does not return an error
+// for trailing data after a member where a comma or the end of input
+// is expected. It parses as many members as possible and returns the
+// remaining input after the last member and whitespace.
+// This is a non-strict mode for embedding a List in a larger grammar.
+// Invalid members are still reported as errors.
+func (p *Parser) ParseListPrefix() (List, string, error) {
+	if err := p.begin(); err != nil {
+		return nil, "", err
+	}
+	p.prefix = true
+	defer func() { p.prefix = false }()
+	list, err := p.parseList()
+	if err != nil {
+		return nil, "", err
+	}
+	return list, p.Remaining(), nil
+}
+
+// ParseItemPrefix is like ParseItem but it does not return an error
+// for trailing data after the item, and returns the remaining input
+// after the item and whitespace instead.
+// This is a non-strict mode for embedding an Item in a larger grammar.
+func (p *Parser) ParseItemPrefix() (Item, string, error) {
+	if err := p.begin(); err != nil {
+		return nil, "", err
+	}
+	start := p.pos
+	it, err := p.parseItem()
+	if err != nil {
+		return nil, "", err
+	}
+	if p.RecordRaw {
+		it.(*item).raw = p.raw(start)
+	}
+	p.skipOWS()
+	return it, p.Remaining(), nil
+}
+
+// Pos returns the byte offset in the input of the next byte to parse.
+// It does not advance the parser.
+func (p *Parser) Pos() int {
+	return p.pos
+}
+
+// Remaining returns the input which is not parsed yet.
+// It does not advance the parser.
+func (p *Parser) Remaining() string {
+	return string(p.input[p.pos:])
+}
+
+// begin checks the limits of the parser and skips the leading
+// whitespace before parsing input. Leading SP is already skipped on
+// construction, and HTAB is skipped here unless Strict is set since
+// it depends on Strict, which is set after construction.
+func (p *Parser) begin() error {
+	if p.MaxInputLen > 0 && len(p.input) > p.MaxInputLen {
+		return &ParseError{
+			msg:  fmt.Sprintf("Input length %d exceeds the limit of %d bytes", len(p.input), p.MaxInputLen),
+			pos:  p.MaxInputLen,
+			kind: ErrTooLarge,
+		}
+	}
+	p.skipSPLenient()
+	return nil
+}
+
 func (p *Parser) parseDictionary() (Dictionary, error) {
 	output := &dictionary{}
 	for !p.eol() {
-		// Dictionary key
-		key, err := p.parseKey()
-		if i := output.index(key); i != -1 {
+		if err := p.checkEmptyMember("dictionary"); err != nil {
+			return nil, err
+		}
+		if p.MaxDictEntries > 0 && output.Len() >= p.MaxDictEntries {
 			return nil, &ParseError{
-				msg: fmt.Sprintf("Duplicate key in dictionary: %s", key),
-				pos: p.pos,
+				msg:  fmt.Sprintf("Dictionary has more than %d members on position %d", p.MaxDictEntries, p.pos),
+				pos:  p.pos,
+				kind: ErrTooLarge,
 			}
 		}
 
-		// Equals sign
-		err = p.matchByte('=')
+		// Dictionary key
+		keyStart := p.pos
+		key, err := p.parseKey("dictionary")
 		if err != nil {
 			return nil, err
 		}
+		if i := output.index(key); i != -1 {
+			return nil, &ParseError{
+				msg:  fmt.Sprintf("Duplicate key in dictionary: %s on position %d", key, keyStart),
+				pos:  keyStart,
+				kind: ErrDuplicateKey,
+			}
+		}
 
-		value, err := p.parseMember()
-		if err != nil {
-			return nil, err
+		var value Member
+		if !p.eol() && p.input[p.pos] == '=' {
+			p.advance()
+			value, err = p.parseMember("dictionary")
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			// A key without a value means boolean true.
+			value, err = p.parseBooleanTrueMember()
+			if err != nil {
+				return nil, err
+			}
 		}
 		output.Store(key, value)
 
@@ -98,9 +432,12 @@ func (p *Parser) parseDictionary() (Dictionary, error) {
 		if p.eol() {
 			return output, nil
 		}
+		if p.prefix && p.input[p.pos] != ',' {
+			return output, nil
+		}
 
 		// Comma for separating values
-		err = p.matchByte(',')
+		err = p.matchByte(',', "dictionary")
 		if err != nil {
 			return nil, err
 		}
@@ -109,8 +446,9 @@ func (p *Parser) parseDictionary() (Dictionary, error) {
 
 		if p.eol() {
 			return nil, &ParseError{
-				msg: "Unexpected end of string",
-				pos: p.pos,
+				msg:  fmt.Sprintf("Unexpected end of string on position %d", p.pos),
+				pos:  p.pos,
+				kind: ErrUnexpectedEOF,
 			}
 		}
 	}
@@ -118,9 +456,19 @@ func (p *Parser) parseDictionary() (Dictionary, error) {
 }
 
 func (p *Parser) parseList() (List, error) {
-	var output []Member
+	output := List{}
 	for !p.eol() {
-		member, err := p.parseMember()
+		if err := p.checkEmptyMember("list"); err != nil {
+			return nil, err
+		}
+		if p.MaxListMembers > 0 && len(output) >= p.MaxListMembers {
+			return nil, &ParseError{
+				msg:  fmt.Sprintf("List has more than %d members on position %d", p.MaxListMembers, p.pos),
+				pos:  p.pos,
+				kind: ErrTooLarge,
+			}
+		}
+		member, err := p.parseMember("list")
 		if err != nil {
 			return nil, err
 		}
@@ -129,7 +477,10 @@ func (p *Parser) parseList() (List, error) {
 		if p.eol() {
 			break
 		}
-		err = p.matchByte(',')
+		if p.prefix && p.input[p.pos] != ',' {
+			break
+		}
+		err = p.matchByte(',', "list")
 		if err != nil {
 			return nil, err
 		}
@@ -137,21 +488,36 @@ func (p *Parser) parseList() (List, error) {
 		p.skipOWS()
 		if p.eol() {
 			return nil, &ParseError{
-				msg: "Unexpected end of string. Was there a trailing comma?",
-				pos: p.pos,
+				msg:  fmt.Sprintf("Unexpected end of string on position %d. Was there a trailing comma?", p.pos),
+				pos:  p.pos,
+				kind: ErrUnexpectedEOF,
 			}
 		}
 	}
 	return output, nil
 }
 
-func (p *Parser) parseMember() (Member, error) {
+// checkEmptyMember returns an error if a comma appears where a member
+// of a list or a dictionary is expected, as in ",a" or "a,,b".
+func (p *Parser) checkEmptyMember(structure string) error {
+	if p.pos < len(p.input) && p.input[p.pos] == ',' {
+		return &ParseError{
+			msg:  fmt.Sprintf("Empty member in %s on position %d", structure, p.pos),
+			pos:  p.pos,
+			kind: ErrInvalidCharacter,
+		}
+	}
+	return nil
+}
+
+func (p *Parser) parseMember(what string) (Member, error) {
 	if p.debug {
 		log.Printf("parseMember enter, rest=%s", string(p.input[p.pos:]))
 		defer log.Printf("parseMember exit, rest=%s", string(p.input[p.pos:]))
 	}
+	start := p.pos
 	var value interface{}
-	b, err := p.peekByte()
+	b, err := p.peekByte(what)
 	if err != nil {
 		return nil, err
 	}
@@ -167,20 +533,48 @@ func (p *Parser) parseMember() (Member, error) {
 		}
 	}
 
-	return &member{
+	m := &member{
 		val: value,
-	}, nil
+	}
+	if p.RecordRaw {
+		m.raw = p.raw(start)
+	}
+	return m, nil
+}
+
+// parseBooleanTrueMember parses the parameters of a dictionary member
+// without a value and returns the member whose value is boolean true.
+func (p *Parser) parseBooleanTrueMember() (Member, error) {
+	start := p.pos
+	params, err := p.parseParameters()
+	if err != nil {
+		return nil, err
+	}
+	m := &member{
+		val: &item{bareItem: &bareItem{val: true}, params: params},
+	}
+	if p.RecordRaw {
+		m.raw = p.raw(start)
+	}
+	return m, nil
+}
+
+// raw returns a copy of the input from start to the current position.
+func (p *Parser) raw(start int) []byte {
+	raw := make([]byte, p.pos-start)
+	copy(raw, p.input[start:p.pos])
+	return raw
 }
 
 func (p *Parser) parseInnerList() (InnerList, error) {
-	err := p.matchByte('(')
+	err := p.matchByte('(', "inner list")
 	if err != nil {
 		return nil, err
 	}
 	var items []Item
-	for !p.eol() {
-		p.skipOWS()
-		b, err := p.peekByte()
+	for {
+		p.skipSPLenient()
+		b, err := p.peekByte("inner list")
 		if err != nil {
 			return nil, err
 		}
@@ -188,19 +582,27 @@ func (p *Parser) parseInnerList() (InnerList, error) {
 			p.advance()
 			break
 		}
+		if limit := p.maxInnerListItems(); limit > 0 && len(items) >= limit {
+			return nil, &ParseError{
+				msg:  fmt.Sprintf("Inner list has more than %d items on position %d", limit, p.pos),
+				pos:  p.pos,
+				kind: ErrTooComplex,
+			}
+		}
 		item, err := p.parseItem()
 		if err != nil {
 			return nil, err
 		}
 		items = append(items, item)
-		b, err = p.peekByte()
+		b, err = p.peekByte("inner list")
 		if err != nil {
 			return nil, err
 		}
-		if b != ' ' && b != ')' {
+		if b != ' ' && b != ')' && (p.Strict || b != '\t') {
 			return nil, &ParseError{
-				msg: "Malformed list. Expected whitespace or )",
-				pos: p.pos,
+				msg:  "Malformed list. Expected whitespace or )",
+				pos:  p.pos,
+				kind: ErrInvalidCharacter,
 			}
 		}
 	}
@@ -214,13 +616,22 @@ func (p *Parser) parseInnerList() (InnerList, error) {
 	}, nil
 }
 
+const defaultMaxInnerListItems = 1024
+
+func (p *Parser) maxInnerListItems() int {
+	if p.MaxInnerListItems == 0 {
+		return defaultMaxInnerListItems
+	}
+	return p.MaxInnerListItems
+}
+
 func (p *Parser) parseItem() (Item, error) {
 	if p.debug {
 		log.Printf("parseItem enter, rest=%s", string(p.input[p.pos:]))
 		defer func() { log.Printf("parseItem exit, rest=%s", string(p.input[p.pos:])) }()
 	}
 
-	bi, err := p.parseBareItem()
+	bi, err := p.parseBareItem("item")
 	if err != nil {
 		return nil, err
 	}
@@ -244,7 +655,7 @@ func (p *Parser) parseParameters() (Parameters, error) {
 
 	params := &parameters{}
 	for !p.eol() {
-		b, err := p.peekByte()
+		b, err := p.peekByte("parameters")
 		if err != nil {
 			return nil, err
 		}
@@ -252,26 +663,29 @@ func (p *Parser) parseParameters() (Parameters, error) {
 			break
 		}
 		p.advance()
-		p.skipOWS()
-		paramKey, err := p.parseKey()
+		p.skipSPLenient()
+		keyStart := p.pos
+		paramKey, err := p.parseKey("parameters")
 		if err != nil {
 			return nil, err
 		}
 		if i := params.index(paramKey); i != -1 {
 			return nil, &ParseError{
-				msg: fmt.Sprintf("Duplicate parameter key: %s", paramKey),
-				pos: p.pos,
+				msg:  fmt.Sprintf("Duplicate parameter key: %s on position %d", paramKey, keyStart),
+				pos:  keyStart,
+				kind: ErrDuplicateKey,
 			}
 		}
-		var paramValue BareItem
+		// A parameter without a value means boolean true.
+		var paramValue BareItem = &bareItem{val: true}
 		if !p.eol() {
-			b, err = p.peekByte()
+			b, err = p.peekByte("parameters")
 			if err != nil {
 				return nil, err
 			}
 			if b == '=' {
 				p.advance()
-				paramValue, err = p.parseBareItem()
+				paramValue, err = p.parseBareItem("parameters")
 				if err != nil {
 					return nil, err
 				}
@@ -282,13 +696,13 @@ func (p *Parser) parseParameters() (Parameters, error) {
 	return params, nil
 }
 
-func (p *Parser) parseBareItem() (BareItem, error) {
+func (p *Parser) parseBareItem(what string) (BareItem, error) {
 	if p.debug {
 		log.Printf("parseBareItem enter, rest=%s", string(p.input[p.pos:]))
 		defer func() { log.Printf("parseBareItem exit, rest=%s", string(p.input[p.pos:])) }()
 	}
 
-	b, err := p.peekByte()
+	b, err := p.peekByte(what)
 	if err != nil {
 		return nil, err
 	}
@@ -299,7 +713,13 @@ func (p *Parser) parseBareItem() (BareItem, error) {
 			return nil, err
 		}
 		return &bareItem{val: v}, nil
-	case b == '*':
+	case b == '%':
+		v, err := p.parseDisplayString()
+		if err != nil {
+			return nil, err
+		}
+		return &bareItem{val: v}, nil
+	case b == p.byteSeqDelimiter():
 		v, err := p.parseByteSeq()
 		if err != nil {
 			return nil, err
@@ -311,13 +731,19 @@ func (p *Parser) parseBareItem() (BareItem, error) {
 			return nil, err
 		}
 		return &bareItem{val: v}, nil
+	case b == '@':
+		v, err := p.parseDate()
+		if err != nil {
+			return nil, err
+		}
+		return &bareItem{val: v}, nil
 	case ('0' <= b && b <= '9') || b == '-':
 		v, err := p.parseNumber()
 		if err != nil {
 			return nil, err
 		}
 		return &bareItem{val: v}, nil
-	case ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z'):
+	case ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || b == '*':
 		v, err := p.parseToken()
 		if err != nil {
 			return nil, err
@@ -325,29 +751,47 @@ func (p *Parser) parseBareItem() (BareItem, error) {
 		return &bareItem{val: v}, nil
 	}
 	return nil, &ParseError{
-		msg: fmt.Sprintf("Unexpected character: %c on position %d", b, p.pos),
-		pos: p.pos,
+		msg:  fmt.Sprintf("Unexpected character: %c on position %d", b, p.pos),
+		pos:  p.pos,
+		kind: ErrInvalidCharacter,
 	}
 }
 
+// ValidString reports whether s is a valid "String" value, that is,
+// s consists only of printable ASCII characters.
+func ValidString(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isStringChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isStringChar reports whether c is allowed in "String" values.
+func isStringChar(c byte) bool {
+	return ' ' <= c && c <= '~'
+}
+
 func (p *Parser) parseString() (string, error) {
 	var out []byte
 	p.advance()
 	for {
-		b, err := p.getByte()
+		b, err := p.getByte("string")
 		if err != nil {
 			return "", err
 		}
 		switch b {
 		case '\\':
-			b2, err := p.getByte()
+			b2, err := p.getByte("string")
 			if err != nil {
 				return "", err
 			}
 			if b2 != '"' && b2 != '\\' {
 				return "", &ParseError{
-					msg: fmt.Sprintf(`Expected a " or \ on position: %d`, p.pos-1),
-					pos: p.pos - 1,
+					msg:  fmt.Sprintf(`Expected a " or \ on position: %d`, p.pos-1),
+					pos:  p.pos - 1,
+					kind: ErrInvalidCharacter,
 				}
 			}
 			out = append(out, b2)
@@ -356,99 +800,326 @@ func (p *Parser) parseString() (string, error) {
 		default:
 			if b < ' ' || b > '~' {
 				return "", &ParseError{
-					msg: "Character outside of ASCII range",
-					pos: p.pos - 1,
+					msg:  "Character outside of ASCII range",
+					pos:  p.pos - 1,
+					kind: ErrInvalidCharacter,
+				}
+			}
+			out = append(out, b)
+		}
+	}
+}
+
+func (p *Parser) parseDisplayString() (DisplayString, error) {
+	if err := p.matchByte('%', "display string"); err != nil {
+		return "", err
+	}
+	if err := p.matchByte('"', "display string"); err != nil {
+		return "", err
+	}
+	var out []byte
+	// positions holds the input position of each byte in out.
+	var positions []int
+	for {
+		start := p.pos
+		b, err := p.getByte("display string")
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case b < ' ' || b > '~':
+			return "", &ParseError{
+				msg:  "Character outside of ASCII range",
+				pos:  start,
+				kind: ErrInvalidCharacter,
+			}
+		case b == '%':
+			hi, err1 := p.getByte("display string")
+			lo, err2 := p.getByte("display string")
+			if err1 != nil || err2 != nil {
+				return "", &ParseError{
+					msg:  "Unexpected end of string in percent-encoded octet",
+					pos:  p.pos,
+					kind: ErrUnexpectedEOF,
+				}
+			}
+			v1, ok1 := lowerHexValue(hi)
+			v2, ok2 := lowerHexValue(lo)
+			if !ok1 || !ok2 {
+				return "", &ParseError{
+					msg:  fmt.Sprintf("Invalid percent-encoded octet on position %d", start),
+					pos:  start,
+					kind: ErrInvalidCharacter,
 				}
 			}
+			out = append(out, v1<<4|v2)
+			positions = append(positions, start)
+		case b == '"':
+			for i := 0; i < len(out); {
+				r, size := utf8.DecodeRune(out[i:])
+				if r == utf8.RuneError && size <= 1 {
+					return "", &ParseError{
+						msg:  fmt.Sprintf("Invalid UTF-8 sequence on position %d", positions[i]),
+						pos:  positions[i],
+						kind: ErrInvalidCharacter,
+					}
+				}
+				i += size
+			}
+			return DisplayString(out), nil
+		default:
 			out = append(out, b)
+			positions = append(positions, start)
 		}
 	}
 }
 
-var tokenRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_\-\.\:\%\*\/]*`)
+func lowerHexValue(b byte) (byte, bool) {
+	switch {
+	case '0' <= b && b <= '9':
+		return b - '0', true
+	case 'a' <= b && b <= 'f':
+		return b - 'a' + 10, true
+	default:
+		return 0, false
+	}
+}
+
+// ValidToken reports whether s is a valid "Token" value.
+func ValidToken(s string) bool {
+	return len(s) != 0 && tokenLen(s) == len(s)
+}
 
 func (p *Parser) parseToken() (Token, error) {
-	m := tokenRegex.Find(p.input[p.pos:])
-	if len(m) == 0 {
+	n := tokenLen(p.input[p.pos:])
+	if n == 0 {
 		return "", &ParseError{
-			msg: fmt.Sprintf("Expected token identifier on position %d", p.pos),
-			pos: p.pos,
+			msg:  fmt.Sprintf("Expected token identifier on position %d", p.pos),
+			pos:  p.pos,
+			kind: ErrInvalidCharacter,
 		}
 	}
-	p.pos += len(m)
+	m := p.input[p.pos : p.pos+n]
+	p.pos += n
 	return Token(m), nil
 }
 
-var keyRegex = regexp.MustCompile(`^[a-z][a-z0-9_\-\*]{0,254}`)
+// tokenLen returns the length of the token at the start of s,
+// or 0 if s does not start with a token.
+//
+//	token = ( ALPHA / "*" ) *( ALPHA / DIGIT / "_" / "-" / "." / ":" / "%" / "*" / "/" )
+func tokenLen[S string | []byte](s S) int {
+	if len(s) == 0 || !(isAlpha(s[0]) || s[0] == '*') {
+		return 0
+	}
+	i := 1
+	for i < len(s) && isTokenChar(s[i]) {
+		i++
+	}
+	return i
+}
 
-func (p *Parser) parseKey() (string, error) {
+func isTokenChar(c byte) bool {
+	switch c {
+	case '_', '-', '.', ':', '%', '*', '/':
+		return true
+	}
+	return isAlpha(c) || isDigit(c)
+}
+
+// maxKeyLen is the maximum length of keys.
+const maxKeyLen = 255
+
+// ValidKey reports whether s is a valid key of Dictionary and Parameters.
+func ValidKey(s string) bool {
+	return len(s) != 0 && keyLen(s, false) == len(s)
+}
+
+// keyLen returns the length of the key at the start of s, or 0 if s
+// does not start with a key. Keys longer than maxKeyLen are truncated.
+// If lenient is true, uppercase letters are also accepted.
+//
+//	key = lcalpha *( lcalpha / DIGIT / "_" / "-" / "*" )
+func keyLen[S string | []byte](s S, lenient bool) int {
+	if len(s) == 0 || !(isLower(s[0]) || lenient && isUpper(s[0])) {
+		return 0
+	}
+	i := 1
+	for i < len(s) && i < maxKeyLen && isKeyChar(s[i], lenient) {
+		i++
+	}
+	return i
+}
+
+func isKeyChar(c byte, lenient bool) bool {
+	switch c {
+	case '_', '-', '*':
+		return true
+	}
+	return isLower(c) || isDigit(c) || lenient && isUpper(c)
+}
+
+func isAlpha(c byte) bool {
+	return isLower(c) || isUpper(c)
+}
+
+func isLower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+func isUpper(c byte) bool {
+	return 'A' <= c && c <= 'Z'
+}
+
+func (p *Parser) parseKey(what string) (string, error) {
 	if p.debug {
 		log.Printf("parseKey enter, rest=%s", string(p.input[p.pos:]))
 		defer func() { log.Printf("parseKey exit, rest=%s", string(p.input[p.pos:])) }()
 	}
 
-	m := keyRegex.Find(p.input[p.pos:])
-	if len(m) == 0 {
+	if p.eol() {
+		return "", p.eofError(what)
+	}
+	n := keyLen(p.input[p.pos:], p.LenientKeys)
+	if n == 0 {
 		return "", &ParseError{
-			msg: fmt.Sprintf("Expected key identifier on position %d", p.pos),
-			pos: p.pos,
+			msg:  fmt.Sprintf("Expected key identifier on position %d", p.pos),
+			pos:  p.pos,
+			kind: ErrInvalidCharacter,
 		}
 	}
-	p.pos += len(m)
+	m := p.input[p.pos : p.pos+n]
+	p.pos += n
+	if p.LenientKeys {
+		return strings.ToLower(string(m)), nil
+	}
 	return string(m), nil
 }
 
-var byteSeqRegex = regexp.MustCompile(`^([A-Za-z0-9\\+\\/=]*)\*`)
+func (p *Parser) byteSeqDelimiter() byte {
+	if p.RFC8941 {
+		return ':'
+	}
+	return '*'
+}
 
 func (p *Parser) parseByteSeq() ([]byte, error) {
-	if err := p.matchByte('*'); err != nil {
-		return nil, err
+	seqStart := p.pos
+	v, err := p.parseByteSeqValue()
+	if err != nil || p.ByteSeqHook == nil {
+		return v, err
 	}
-	m := byteSeqRegex.FindSubmatch(p.input[p.pos:])
-	if len(m) == 0 {
+	v, err = p.ByteSeqHook(v)
+	if err != nil {
 		return nil, &ParseError{
-			msg: fmt.Sprintf("Couldn't parse byte sequence at position %d", p.pos),
-			pos: p.pos,
+			msg: fmt.Sprintf("Byte sequence rejected on position %d: %s", seqStart, err),
+			pos: seqStart,
+			err: err,
 		}
 	}
-	// encodedLen := len(m[1])
-	// if encodedLen%4 != 0 {
-	// 	return nil, &ParseError{
-	// 		msg: fmt.Sprintf("Base64 strings should always have a length that's a multiple of 4. Did you forget padding at position %d?", p.pos),
-	// 		pos: p.pos,
-	// 	}
-	// }
-	p.pos += len(m[0])
+	return v, nil
+}
+
+func (p *Parser) parseByteSeqValue() ([]byte, error) {
+	if err := p.matchByte(p.byteSeqDelimiter(), "byte sequence"); err != nil {
+		return nil, err
+	}
+	start := p.pos
+	for p.pos < len(p.input) && isBase64Char(p.input[p.pos]) {
+		p.pos++
+	}
+	src := p.input[start:p.pos]
+	if err := p.matchByte(p.byteSeqDelimiter(), "byte sequence"); err != nil {
+		return nil, err
+	}
+
+	enc, padded, ok := p.ByteSeqEncoding.codec(len(src))
+	if !padded {
+		// Unpadded input is decoded only if it has no padding and
+		// its length is possible for unpadded input.
+		if i := bytes.IndexByte(src, '='); i != -1 || !ok {
+			pos := start + len(src)
+			if i != -1 {
+				pos = start + i
+			}
+			return nil, &ParseError{
+				msg:  fmt.Sprintf("Invalid %s length or padding at position %d", p.ByteSeqEncoding, pos),
+				pos:  pos,
+				kind: ErrInvalidCharacter,
+			}
+		}
+	}
+	return p.decodeByteSeq(src, enc, start)
+}
+
+func isBase64Char(b byte) bool {
+	return ('A' <= b && b <= 'Z') || ('a' <= b && b <= 'z') ||
+		('0' <= b && b <= '9') || b == '+' || b == '/' || b == '='
+}
 
-	src := m[1]
-	dst, err := p.decodeBase64(src, base64.StdEncoding)
+// decodeByteSeq decodes src with enc. start is the position of src
+// in the input, which is used for the error position.
+//
+// src is decoded into the reusable buffer of the parser, which is
+// overwritten by the next call, so the result is copied into a new
+// slice of the exact length.
+func (p *Parser) decodeByteSeq(src []byte, enc byteSeqCodec, start int) ([]byte, error) {
+	// The limit is checked before decoding so that the buffer is not
+	// grown for a value exceeding it.
+	if n := p.ByteSeqEncoding.decodedLen(src); p.MaxByteSeqLen > 0 && n > p.MaxByteSeqLen {
+		// start-1 is the position of the opening delimiter.
+		return nil, &ParseError{
+			msg:  fmt.Sprintf("Byte sequence of %d bytes exceeds the limit of %d bytes on position %d", n, p.MaxByteSeqLen, start-1),
+			pos:  start - 1,
+			kind: ErrTooLarge,
+		}
+	}
+	if n := enc.DecodedLen(len(src)); cap(p.decodeBuf) < n {
+		p.decodeBuf = make([]byte, n)
+	}
+	buf := p.decodeBuf[:cap(p.decodeBuf)]
+	n, err := enc.Decode(buf, src)
 	if err != nil {
-		dst, err = p.decodeBase64(src, base64.RawStdEncoding)
-		if err != nil {
-			return nil, err
+		pos := start
+		if off, ok := corruptInputOffset(err); ok && int(off) <= len(src) {
+			pos += int(off)
+		}
+		return nil, &ParseError{
+			msg:  fmt.Sprintf("Invalid %s strings at position %d", p.ByteSeqEncoding, pos),
+			pos:  pos,
+			kind: ErrInvalidCharacter,
 		}
 	}
+	dst := make([]byte, n)
+	copy(dst, buf[:n])
 	return dst, nil
 }
 
-func (p *Parser) decodeBase64(src []byte, enc *base64.Encoding) ([]byte, error) {
-	encodedLen := len(src)
-	dst := make([]byte, enc.DecodedLen(encodedLen))
-	n, err := enc.Decode(dst, src)
+func (p *Parser) parseDate() (time.Time, error) {
+	if err := p.matchByte('@', "date"); err != nil {
+		return time.Time{}, err
+	}
+	start := p.pos
+	v, err := p.parseNumber()
 	if err != nil {
-		return nil, &ParseError{
-			msg: fmt.Sprintf("Invalid base64 strings at position %d?", p.pos),
-			pos: p.pos,
+		return time.Time{}, err
+	}
+	sec, ok := v.(int64)
+	if !ok {
+		return time.Time{}, &ParseError{
+			msg:  fmt.Sprintf("Dates must be integers on position %d", start),
+			pos:  start,
+			kind: ErrInvalidCharacter,
 		}
 	}
-	return dst[:n], nil
+	return time.Unix(sec, 0).UTC(), nil
 }
 
 func (p *Parser) parseBoolean() (bool, error) {
-	if err := p.matchByte('?'); err != nil {
+	if err := p.matchByte('?', "boolean"); err != nil {
 		return false, err
 	}
-	b, err := p.getByte()
+	b, err := p.getByte("boolean")
 	if err != nil {
 		return false, err
 	}
@@ -459,65 +1130,166 @@ func (p *Parser) parseBoolean() (bool, error) {
 		return true, nil
 	default:
 		return false, &ParseError{
-			msg: `A "?" must be followed by "0" or "1"`,
-			pos: p.pos - 1,
+			msg:  `A "?" must be followed by "0" or "1"`,
+			pos:  p.pos - 1,
+			kind: ErrInvalidCharacter,
 		}
 	}
 }
 
-var numberPartRegex = regexp.MustCompile(`^[0-9-]([0-9])*(\.[0-9]{1,6})?`)
+// decimalMaxFracDigits is the maximum number of fractional digits of
+// "Decimal" values in input.
+const decimalMaxFracDigits = 3
 
+// parseNumber parses an "Integer" or a "Decimal" with the grammar
+// below and returns an int64 or a Decimal. If RoundFractionalDigits
+// is true, any number of fractional digits is accepted.
+//
+//	number  = [ "-" ] 1*DIGIT [ "." 1*3DIGIT ]
 func (p *Parser) parseNumber() (interface{}, error) {
-	m := numberPartRegex.Find(p.input[p.pos:])
-	if len(m) == 0 {
+	start := p.pos
+	if p.pos < len(p.input) && p.input[p.pos] == '-' {
+		p.pos++
+	}
+	intStart := p.pos
+	p.skipDigits()
+	if p.pos == intStart {
+		return nil, p.expectedDigitError("Expected digit", start)
+	}
+	if p.RejectLeadingZeros && p.input[intStart] == '0' && p.pos-intStart > 1 {
 		return nil, &ParseError{
-			msg: fmt.Sprintf("Expected number on position %d", p.pos),
-			pos: p.pos,
+			msg:  fmt.Sprintf("Leading zeros are not allowed on position %d", intStart),
+			pos:  intStart,
+			kind: ErrInvalidCharacter,
 		}
 	}
-	p.pos += len(m)
-	if bytes.IndexByte(m, '.') != -1 {
-		v, err := strconv.ParseFloat(string(m), 64)
-		if err != nil {
+	if p.pos < len(p.input) && p.input[p.pos] == '.' {
+		dot := p.pos - start
+		p.pos++
+		fracStart := p.pos
+		p.skipDigits()
+		if p.pos == fracStart {
+			return nil, p.expectedDigitError("Expected fractional digit", start)
+		}
+		if p.pos-fracStart > decimalMaxFracDigits && !p.RoundFractionalDigits {
+			pos := fracStart + decimalMaxFracDigits
 			return nil, &ParseError{
-				msg: fmt.Sprintf("Expected float number on position %d", p.pos),
-				pos: p.pos,
+				msg:  fmt.Sprintf("Decimals must not have more than %d fractional digits on position %d", decimalMaxFracDigits, pos),
+				pos:  pos,
+				kind: ErrInvalidCharacter,
 			}
 		}
-		return v, nil
+		return p.parseDecimal(start, p.input[start:p.pos], dot)
 	}
-	if len(m) > 16 || (m[0] != '-' && len(m) > 15) {
+
+	limit := integerDigitLimit(p.IntegerDigitLimit)
+	if digits := p.input[intStart:p.pos]; len(digits) > limit {
+		pos := intStart + limit
 		return nil, &ParseError{
-			msg: "Integers must not have more than 15 digits",
-			pos: p.pos,
+			msg: fmt.Sprintf("integer out of range, more than %d digits on position %d", limit, pos),
+			pos: pos,
+			err: p.numError("ParseInt", start, strconv.ErrRange),
 		}
 	}
-	v, err := strconv.ParseInt(string(m), 10, 64)
+	v, err := strconv.ParseInt(string(p.input[start:p.pos]), 10, 64)
+	if err == nil && !integerInRange(v, limit) {
+		err = p.numError("ParseInt", start, strconv.ErrRange)
+	}
 	if err != nil {
 		return nil, &ParseError{
-			msg: fmt.Sprintf("Expected integer number on position %d", p.pos),
-			pos: p.pos,
+			msg: fmt.Sprintf("integer out of range on position %d", start),
+			pos: start,
+			err: err,
 		}
 	}
 	return v, nil
 }
 
-func (p *Parser) matchByte(match byte) error {
-	b, err := p.getByte()
+func (p *Parser) skipDigits() {
+	for p.pos < len(p.input) && isDigit(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// expectedDigitError returns an error for a missing digit on the
+// current position. start is the position of the number.
+func (p *Parser) expectedDigitError(msg string, start int) error {
+	if p.eol() {
+		return &ParseError{
+			msg:  fmt.Sprintf("%s but reached end of string on position %d", msg, p.pos),
+			pos:  p.pos,
+			kind: ErrUnexpectedEOF,
+			err:  p.numError("ParseInt", start, strconv.ErrSyntax),
+		}
+	}
+	return &ParseError{
+		msg:  fmt.Sprintf("%s on position %d", msg, p.pos),
+		pos:  p.pos,
+		kind: ErrInvalidCharacter,
+		err:  p.numError("ParseInt", start, strconv.ErrSyntax),
+	}
+}
+
+// numError returns a *strconv.NumError for the number from start to
+// the current position, as strconv functions return.
+func (p *Parser) numError(fn string, start int, err error) *strconv.NumError {
+	return &strconv.NumError{Func: fn, Num: string(p.input[start:p.pos]), Err: err}
+}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
+
+func (p *Parser) parseDecimal(start int, m []byte, dot int) (Decimal, error) {
+	intPart := m[:dot]
+	if m[0] == '-' {
+		intPart = intPart[1:]
+	}
+	if len(intPart) > decimalIntDigits {
+		pos := start + dot - len(intPart) + decimalIntDigits
+		return Decimal{}, &ParseError{
+			msg: fmt.Sprintf("Decimals must not have more than %d integer digits on position %d", decimalIntDigits, pos),
+			pos: pos,
+			err: p.numError("ParseFloat", start, strconv.ErrRange),
+		}
+	}
+	milli, err := roundToMilli(string(intPart), string(m[dot+1:]))
+	if err == nil && milli > maxDecimalMilliAbs {
+		err = p.numError("ParseFloat", start, strconv.ErrRange)
+	}
+	if err != nil {
+		return Decimal{}, &ParseError{
+			msg: fmt.Sprintf("Expected decimal number on position %d", start),
+			pos: start,
+			err: err,
+		}
+	}
+	if m[0] == '-' {
+		milli = -milli
+	}
+	return NewDecimal(milli), nil
+}
+
+// matchByte consumes the next byte if it is match, and returns an
+// error otherwise. what describes the value being parsed for the
+// error message on the end of input.
+func (p *Parser) matchByte(match byte, what string) error {
+	b, err := p.getByte(what)
 	if err != nil {
 		return err
 	}
 	if b != match {
 		return &ParseError{
-			msg: fmt.Sprintf("Expected %c on position %d", match, p.pos-1),
-			pos: p.pos - 1,
+			msg:  fmt.Sprintf("Expected %c on position %d", match, p.pos-1),
+			pos:  p.pos - 1,
+			kind: ErrInvalidCharacter,
 		}
 	}
 	return nil
 }
 
-func (p *Parser) getByte() (byte, error) {
-	b, err := p.peekByte()
+func (p *Parser) getByte(what string) (byte, error) {
+	b, err := p.peekByte(what)
 	if err != nil {
 		return 0, err
 	}
@@ -525,32 +1297,58 @@ func (p *Parser) getByte() (byte, error) {
 	return b, nil
 }
 
-func (p *Parser) peekByte() (byte, error) {
-	if len(p.input[p.pos:]) == 0 {
-		// panic("Unexpected end of string in peekByte")
-		return 0, &ParseError{
-			msg: "Unexpected end of string in peekByte",
-			pos: p.pos,
-		}
+func (p *Parser) peekByte(what string) (byte, error) {
+	if p.eol() {
+		return 0, p.eofError(what)
 	}
 	return p.input[p.pos], nil
 }
 
+// eofError returns an error for the unexpected end of input while
+// parsing the value described by what.
+func (p *Parser) eofError(what string) error {
+	return &ParseError{
+		msg:  fmt.Sprintf("unexpected end of input while parsing %s", what),
+		pos:  p.pos,
+		kind: ErrUnexpectedEOF,
+	}
+}
+
 func (p *Parser) advance() {
 	p.pos++
 }
 
 func (p *Parser) end() error {
-	p.skipOWS()
+	p.skipSPLenient()
 	if !p.eol() {
 		return &ParseError{
-			msg: "Expected end of the string, but found more data instead",
-			pos: p.pos,
+			msg:  fmt.Sprintf("Expected end of the string, but found more data instead on position %d", p.pos),
+			pos:  p.pos,
+			kind: ErrInvalidCharacter,
 		}
 	}
 	return nil
 }
 
+func (p *Parser) skipSP() {
+	for len(p.input[p.pos:]) > 0 && p.input[p.pos] == ' ' {
+		p.advance()
+	}
+}
+
+// skipSPLenient skips SP where the specification allows only SP,
+// that is, around the whole input, after ";" of parameters and inside
+// inner lists. HTAB is also skipped unless Strict is set.
+func (p *Parser) skipSPLenient() {
+	if p.Strict {
+		p.skipSP()
+	} else {
+		p.skipOWS()
+	}
+}
+
+// skipOWS skips SP and HTAB, which are allowed around "," of
+// List and Dictionary.
 func (p *Parser) skipOWS() {
 	for len(p.input[p.pos:]) > 0 {
 		b := p.input[p.pos]
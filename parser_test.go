@@ -0,0 +1,1542 @@
+package stheader_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestByteSeqDelimiter(t *testing.T) {
+	testCases := []struct {
+		input   string
+		rfc8941 bool
+	}{
+		{input: "*aGVsbG8=*", rfc8941: false},
+		{input: ":aGVsbG8=:", rfc8941: true},
+	}
+	for _, c := range testCases {
+		p := stheader.NewParser(c.input)
+		p.RFC8941 = c.rfc8941
+		item, err := p.ParseItem()
+		if err != nil {
+			t.Fatalf("parse %q: %s", c.input, err)
+		}
+		if got, want := string(item.BareItem().AsByteSeq()), "hello"; got != want {
+			t.Errorf("value mismatch for %q, got=%q, want=%q", c.input, got, want)
+		}
+
+		s := stheader.Serializer{RFC8941: c.rfc8941}
+		got, err := s.SerializeItem(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.input {
+			t.Errorf("serialize mismatch, got=%q, want=%q", got, c.input)
+		}
+
+		p = stheader.NewParser(c.input)
+		p.RFC8941 = !c.rfc8941
+		if _, err := p.ParseItem(); err == nil {
+			t.Errorf("should fail to parse %q with RFC8941=%v", c.input, !c.rfc8941)
+		}
+	}
+}
+
+func TestParseIntegerDigitLimit(t *testing.T) {
+	testCases := []struct {
+		input   string
+		limit   int
+		want    int64
+		wantErr bool
+		errPos  int
+	}{
+		{input: "999999999999999", want: 999_999_999_999_999},
+		{input: "-999999999999999", want: -999_999_999_999_999},
+		{input: "1000000000000000", wantErr: true, errPos: 15},
+		{input: "-1000000000000000", wantErr: true, errPos: 16},
+		{input: "999999999999", limit: 12, want: 999_999_999_999},
+		{input: "-999999999999", limit: 12, want: -999_999_999_999},
+		{input: "1000000000000", limit: 12, wantErr: true, errPos: 12},
+		{input: "-1000000000000", limit: 12, wantErr: true, errPos: 13},
+	}
+	for _, c := range testCases {
+		p := stheader.NewParser(c.input)
+		p.IntegerDigitLimit = c.limit
+		item, err := p.ParseItem()
+		if c.wantErr {
+			perr, ok := err.(*stheader.ParseError)
+			if !ok {
+				t.Errorf("should fail with ParseError for %q (limit=%d), got=%v", c.input, c.limit, err)
+				continue
+			}
+			if got, want := perr.Pos(), c.errPos; got != want {
+				t.Errorf("error position mismatch for %q, got=%d, want=%d", c.input, got, want)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q (limit=%d): %s", c.input, c.limit, err)
+			continue
+		}
+		if got := item.BareItem().AsInt(); got != c.want {
+			t.Errorf("value mismatch, got=%d, want=%d", got, c.want)
+		}
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	testCases := []struct {
+		input     string
+		wantMilli int64
+		want      string
+	}{
+		{input: "3.142", wantMilli: 3142, want: "3.142"},
+		{input: "-3.142", wantMilli: -3142, want: "-3.142"},
+		{input: "1.50", wantMilli: 1500, want: "1.5"},
+		{input: "0.0", wantMilli: 0, want: "0.0"},
+		{input: "999999999999.999", wantMilli: 999_999_999_999_999, want: "999999999999.999"},
+	}
+	for _, c := range testCases {
+		item, err := stheader.NewParser(c.input).ParseItem()
+		if err != nil {
+			t.Fatalf("parse %q: %s", c.input, err)
+		}
+		bi := item.BareItem()
+		if got, want := bi.Type(), stheader.ItemTypeDecimal; got != want {
+			t.Fatalf("type mismatch for %q, got=%s, want=%s", c.input, got, want)
+		}
+		if got := bi.AsDecimal().Milli(); got != c.wantMilli {
+			t.Errorf("value mismatch for %q, got=%d, want=%d", c.input, got, c.wantMilli)
+		}
+		got, err := stheader.Serialize(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("serialize mismatch for %q, got=%q, want=%q", c.input, got, c.want)
+		}
+	}
+
+	if _, err := stheader.NewParser("1000000000000.0").ParseItem(); err == nil {
+		t.Error("should fail for a decimal with more than 12 integer digits")
+	}
+}
+
+func TestValidToken(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  bool
+	}{
+		{input: "*", want: true},
+		{input: "a", want: true},
+		{input: "foo123", want: true},
+		{input: "*foo", want: true},
+		{input: "text/html", want: true},
+		{input: "", want: false},
+		{input: "1foo", want: false},
+		{input: "foo bar", want: false},
+		{input: "foo,", want: false},
+	}
+	for _, c := range testCases {
+		if got := stheader.ValidToken(c.input); got != c.want {
+			t.Errorf("ValidToken mismatch for %q, got=%v, want=%v", c.input, got, c.want)
+		}
+	}
+}
+
+func TestTokenWithLeadingAsterisk(t *testing.T) {
+	p := stheader.NewParser("*foo")
+	p.RFC8941 = true
+	item, err := p.ParseItem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := item.BareItem().AsToken(), stheader.Token("*foo"); got != want {
+		t.Errorf("token mismatch, got=%q, want=%q", got, want)
+	}
+
+	s := stheader.Serializer{RFC8941: true}
+	got, err := s.SerializeItem(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "*foo"; got != want {
+		t.Errorf("serialize mismatch, got=%q, want=%q", got, want)
+	}
+
+	if _, err := stheader.Serialize(item); err == nil {
+		t.Error("should fail to serialize a token starting with * in draft-14 mode")
+	}
+}
+
+func TestTokenWithLeadingAsteriskRoundTrip(t *testing.T) {
+	item := stheader.NewItem(stheader.NewBareItem(stheader.Token("*foo")), nil)
+	testCases := []struct {
+		serializerRFC8941 bool
+		parserRFC8941     bool
+		want              bool
+	}{
+		{serializerRFC8941: false, parserRFC8941: false, want: false},
+		{serializerRFC8941: false, parserRFC8941: true, want: false},
+		{serializerRFC8941: true, parserRFC8941: false, want: false},
+		{serializerRFC8941: true, parserRFC8941: true, want: true},
+	}
+	for _, c := range testCases {
+		s := stheader.Serializer{RFC8941: c.serializerRFC8941}
+		p := stheader.NewParser("")
+		p.RFC8941 = c.parserRFC8941
+		got := false
+		if v, err := s.SerializeItem(item); err == nil {
+			p.Reset(v)
+			parsed, err := p.ParseItem()
+			got = err == nil && stheader.Equal(parsed, item)
+		}
+		if got != c.want {
+			t.Errorf("round trip mismatch with serializer RFC8941=%v and parser RFC8941=%v, got=%v, want=%v",
+				c.serializerRFC8941, c.parserRFC8941, got, c.want)
+		}
+	}
+}
+
+func TestValidKey(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  bool
+	}{
+		{input: "a", want: true},
+		{input: "foo-bar_1*", want: true},
+		{input: strings.Repeat("a", 255), want: true},
+		{input: "", want: false},
+		{input: "Foo", want: false},
+		{input: "1foo", want: false},
+		{input: "foo bar", want: false},
+		{input: strings.Repeat("a", 256), want: false},
+	}
+	for _, c := range testCases {
+		if got := stheader.ValidKey(c.input); got != c.want {
+			t.Errorf("ValidKey mismatch for %q, got=%v, want=%v", c.input, got, c.want)
+		}
+	}
+}
+
+func TestNewParserBytes(t *testing.T) {
+	input := []byte(`a=foo, b="bar";p=*aGk=*`)
+	dict, err := stheader.NewParserBytes(input).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range input {
+		input[i] = 'x'
+	}
+	if got, want := fmt.Sprint(dict), `a=foo, b="bar";p=*aGk=*`; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+}
+
+func TestParserReset(t *testing.T) {
+	p := stheader.NewParser(":aGk=:, foo")
+	p.RFC8941 = true
+	first, err := p.ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Reset(" :aGo=:")
+	second, err := p.ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(first[0].AsItem().BareItem().AsByteSeq()), "hi"; got != want {
+		t.Errorf("first result mismatch, got=%q, want=%q", got, want)
+	}
+	if got, want := string(second[0].AsItem().BareItem().AsByteSeq()), "hj"; got != want {
+		t.Errorf("second result mismatch, got=%q, want=%q", got, want)
+	}
+}
+
+const benchmarkDictionary = `a=1, b="foo";p=?0, c=(x y z);q=1.5, d=*aGVsbG8=*`
+
+func BenchmarkNewParser(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p := stheader.NewParser(benchmarkDictionary)
+		if _, err := p.ParseDictionary(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParserReset(b *testing.B) {
+	b.ReportAllocs()
+	input := []byte(benchmarkDictionary)
+	p := stheader.NewParserBytes(nil)
+	for i := 0; i < b.N; i++ {
+		p.ResetBytes(input)
+		if _, err := p.ParseDictionary(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParseErrorContext(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  string
+	}{
+		{
+			input: "a=1, b=?2, c=3",
+			want:  "a=1, b=?2, c=3\n        ^",
+		},
+		{
+			input: `a="` + strings.Repeat("x", 30) + "\t" + strings.Repeat("y", 30) + `"`,
+			want: "..." + strings.Repeat("x", 20) + "." + strings.Repeat("y", 19) + "...\n" +
+				strings.Repeat(" ", 23) + "^",
+		},
+	}
+	for _, c := range testCases {
+		_, err := stheader.NewParser(c.input).ParseDictionary()
+		perr, ok := err.(*stheader.ParseError)
+		if !ok {
+			t.Fatalf("should fail with ParseError for %q, got=%v", c.input, err)
+		}
+		got := perr.Context(c.input)
+		if got != c.want {
+			t.Errorf("context mismatch for %q,\ngot:\n%s\nwant:\n%s", c.input, got, c.want)
+		}
+		lines := strings.Split(got, "\n")
+		if caret := strings.IndexByte(lines[1], '^'); lines[0][caret] != c.input[perr.Pos()] && lines[0][caret] != '.' {
+			t.Errorf("caret does not point at the error position for %q", c.input)
+		}
+	}
+}
+
+func TestParseErrorIs(t *testing.T) {
+	testCases := []struct {
+		input      string
+		headerType string
+		want       error
+	}{
+		{input: `a=1, a=2`, headerType: "dictionary", want: stheader.ErrDuplicateKey},
+		{input: `1;a;a`, headerType: "item", want: stheader.ErrDuplicateKey},
+		{input: `a=1,`, headerType: "dictionary", want: stheader.ErrUnexpectedEOF},
+		{input: `"foo`, headerType: "item", want: stheader.ErrUnexpectedEOF},
+		{input: `a, b,`, headerType: "list", want: stheader.ErrUnexpectedEOF},
+		{input: `a=?2`, headerType: "dictionary", want: stheader.ErrInvalidCharacter},
+		{input: `a b`, headerType: "list", want: stheader.ErrInvalidCharacter},
+		{input: `"foo\x"`, headerType: "item", want: stheader.ErrInvalidCharacter},
+	}
+	for _, c := range testCases {
+		_, err := parse(c.headerType, c.input)
+		if !errors.Is(err, c.want) {
+			t.Errorf("error mismatch for %q, got=%v, want=%v", c.input, err, c.want)
+		}
+	}
+}
+
+func TestParseByteSeqPadding(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    string
+		wantErr bool
+		errPos  int
+	}{
+		{input: "*aGVsbG8=*", want: "hello"},
+		{input: "*aGVsbG8*", want: "hello"},
+		{input: "*aGk=*", want: "hi"},
+		{input: "*aGk*", want: "hi"},
+		{input: "**", want: ""},
+		{input: "*aGVsb*", wantErr: true, errPos: 6},
+		{input: "*aGVsbG8==*", wantErr: true, errPos: 8},
+		{input: "*aG=k*", wantErr: true, errPos: 3},
+		{input: "*aGk=aGk=*", wantErr: true, errPos: 5},
+	}
+	for _, c := range testCases {
+		item, err := stheader.NewParser(c.input).ParseItem()
+		if c.wantErr {
+			perr, ok := err.(*stheader.ParseError)
+			if !ok {
+				t.Errorf("should fail with ParseError for %q, got=%v", c.input, err)
+				continue
+			}
+			if got, want := perr.Pos(), c.errPos; got != want {
+				t.Errorf("error position mismatch for %q, got=%d, want=%d", c.input, got, want)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", c.input, err)
+			continue
+		}
+		if got := string(item.BareItem().AsByteSeq()); got != c.want {
+			t.Errorf("value mismatch for %q, got=%q, want=%q", c.input, got, c.want)
+		}
+	}
+}
+
+func TestParseWhitespace(t *testing.T) {
+	testCases := []struct {
+		name       string
+		raw        string
+		headerType string
+		mustFail   bool
+		strictFail bool
+	}{
+		{name: "space after semicolon", raw: "a; b=1", headerType: "list"},
+		{name: "tab after semicolon", raw: "a;\tb=1", headerType: "list", strictFail: true},
+		{name: "space before semicolon", raw: "a ;b=1", headerType: "list", mustFail: true},
+		{name: "space before semicolon in inner list", raw: "(a ;b=1)", headerType: "list", mustFail: true},
+		{name: "tab after semicolon of inner list", raw: "(a b);\tc", headerType: "list", strictFail: true},
+		{name: "whitespace around comma", raw: "a=1 \t, \tb=2", headerType: "dictionary"},
+		{name: "space before equals", raw: "a =1", headerType: "dictionary", mustFail: true},
+		{name: "space after equals", raw: "a= 1", headerType: "dictionary", mustFail: true},
+		{name: "space after equals of parameter", raw: "a;b= 1", headerType: "list", mustFail: true},
+		{name: "unterminated inner list", raw: "(", headerType: "list", mustFail: true},
+		{name: "unterminated inner list with items", raw: "(1 2 ", headerType: "list", mustFail: true},
+		{name: "invalid dictionary key", raw: "a=1, 2=3", headerType: "dictionary", mustFail: true},
+		{name: "leading spaces", raw: "  a, b", headerType: "list"},
+		{name: "leading tab", raw: "\ta, b", headerType: "list", strictFail: true},
+		{name: "trailing tab after list", raw: "a, b\t", headerType: "list"},
+		{name: "tab around comma of list", raw: "a\t,\tb", headerType: "list"},
+		{name: "trailing tab after item", raw: "a\t", headerType: "item", strictFail: true},
+		{name: "leading tab before item", raw: "\ta", headerType: "item", strictFail: true},
+		{name: "trailing spaces after item", raw: "a  ", headerType: "item"},
+		{name: "multiple spaces in inner list", raw: "(  1  2  )", headerType: "list"},
+		{name: "tab between inner list items", raw: "(1\t2)", headerType: "list", strictFail: true},
+		{name: "tab after space in inner list", raw: "(1 \t2)", headerType: "list", strictFail: true},
+		{name: "tab at start of inner list", raw: "(\t1 2)", headerType: "list", strictFail: true},
+		{name: "tab at end of inner list", raw: "(1 2 \t)", headerType: "list", strictFail: true},
+	}
+	for _, c := range testCases {
+		for _, strict := range []bool{false, true} {
+			p := stheader.NewParser(c.raw)
+			p.Strict = strict
+			var err error
+			switch c.headerType {
+			case "list":
+				_, err = p.ParseList()
+			case "dictionary":
+				_, err = p.ParseDictionary()
+			case "item":
+				_, err = p.ParseItem()
+			}
+			wantFail := c.mustFail || (strict && c.strictFail)
+			if wantFail && err == nil {
+				t.Errorf("%s: should fail for %q (strict=%v)", c.name, c.raw, strict)
+			} else if !wantFail && err != nil {
+				t.Errorf("%s: unexpected error for %q (strict=%v): %s", c.name, c.raw, strict, err)
+			}
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  time.Time
+	}{
+		{input: "@0", want: time.Unix(0, 0)},
+		{input: "@-1000", want: time.Unix(-1000, 0)},
+		{input: "@1659578233", want: time.Date(2022, 8, 4, 1, 57, 13, 0, time.UTC)},
+	}
+	for _, c := range testCases {
+		item, err := stheader.NewParser(c.input).ParseItem()
+		if err != nil {
+			t.Fatalf("parse %q: %s", c.input, err)
+		}
+		bi := item.BareItem()
+		if got, want := bi.Type(), stheader.ItemTypeDate; got != want {
+			t.Fatalf("type mismatch for %q, got=%s, want=%s", c.input, got, want)
+		}
+		if got := bi.AsDate(); !got.Equal(c.want) {
+			t.Errorf("value mismatch for %q, got=%s, want=%s", c.input, got, c.want)
+		}
+		got, err := stheader.Serialize(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.input {
+			t.Errorf("serialize mismatch, got=%q, want=%q", got, c.input)
+		}
+	}
+
+	for _, input := range []string{"@1.5", "@", "@a", "@1000000000000000"} {
+		if _, err := stheader.NewParser(input).ParseItem(); err == nil {
+			t.Errorf("should fail for %q", input)
+		}
+	}
+}
+
+func TestParseDisplayString(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  stheader.DisplayString
+	}{
+		{input: `%"foo bar"`, want: "foo bar"},
+		{input: `%"f%c3%bc%c3%bc"`, want: "füü"},
+		{input: `%"%f0%9f%98%80 smile"`, want: "😀 smile"},
+		{input: `%"%e6%97%a5%e6%9c%ac%e8%aa%9e"`, want: "日本語"},
+		{input: `%"100%25 %22ok%22"`, want: `100% "ok"`},
+	}
+	for _, c := range testCases {
+		item, err := stheader.NewParser(c.input).ParseItem()
+		if err != nil {
+			t.Fatalf("parse %q: %s", c.input, err)
+		}
+		if got := item.BareItem().AsDisplayString(); got != c.want {
+			t.Errorf("value mismatch for %q, got=%q, want=%q", c.input, got, c.want)
+		}
+		got, err := stheader.Serialize(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.input {
+			t.Errorf("serialize mismatch, got=%q, want=%q", got, c.input)
+		}
+	}
+}
+
+func TestParseDisplayStringError(t *testing.T) {
+	testCases := []struct {
+		input  string
+		errPos int
+	}{
+		{input: `%"foo`, errPos: 5},
+		{input: `%"a%zzb"`, errPos: 3},
+		{input: `%"a%C3%BC"`, errPos: 3},
+		{input: `%"ab%c3"`, errPos: 4},
+		{input: `%"ab%ff%c3%bc"`, errPos: 4},
+		{input: "%\"a\tb\"", errPos: 3},
+		{input: `%foo`, errPos: 1},
+	}
+	for _, c := range testCases {
+		_, err := stheader.NewParser(c.input).ParseItem()
+		perr, ok := err.(*stheader.ParseError)
+		if !ok {
+			t.Errorf("should fail with ParseError for %q, got=%v", c.input, err)
+			continue
+		}
+		if got, want := perr.Pos(), c.errPos; got != want {
+			t.Errorf("error position mismatch for %q, got=%d, want=%d", c.input, got, want)
+		}
+	}
+}
+
+func TestParseRecordRaw(t *testing.T) {
+	const input = `1.50;x="y",  (foo   bar) ,c`
+	p := stheader.NewParser(input)
+	p.RecordRaw = true
+	list, err := p.ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`1.50;x="y"`, `(foo   bar)`, `c`}
+	for i, m := range list {
+		got := string(m.RawBytes())
+		if got != want[i] {
+			t.Errorf("raw mismatch for member %d, got=%q, want=%q", i, got, want[i])
+		}
+		if !strings.Contains(input, got) {
+			t.Errorf("raw should be a substring of the input, got=%q", got)
+		}
+	}
+
+	p = stheader.NewParser(`a=(1  2), b=?1;p`)
+	p.RecordRaw = true
+	dict, err := p.ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := dict.Load("a")
+	if got, want := string(a.RawBytes()), "(1  2)"; got != want {
+		t.Errorf("raw mismatch, got=%q, want=%q", got, want)
+	}
+
+	p = stheader.NewParser(` 01.500;p `)
+	p.RecordRaw = true
+	item, err := p.ParseItem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(item.RawBytes()), "01.500;p"; got != want {
+		t.Errorf("raw mismatch, got=%q, want=%q", got, want)
+	}
+
+	item, err = stheader.NewParser(`1`).ParseItem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.RawBytes() != nil {
+		t.Errorf("raw should be nil without RecordRaw, got=%q", item.RawBytes())
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	list, err := stheader.NewParser("").ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list == nil || len(list) != 0 {
+		t.Errorf("list should be empty but not nil, got=%#v", list)
+	}
+
+	dict, err := stheader.NewParser("").ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dict == nil || dict.Len() != 0 {
+		t.Errorf("dictionary should be empty but not nil, got=%#v", dict)
+	}
+
+	if _, err := stheader.NewParser("").ParseItem(); err == nil {
+		t.Error("should fail for an empty item")
+	}
+}
+
+func TestParseEmptyMember(t *testing.T) {
+	testCases := []struct {
+		input      string
+		headerType string
+		pos        int
+		want       error
+	}{
+		{input: `,a`, headerType: "list", pos: 0, want: stheader.ErrInvalidCharacter},
+		{input: `a,,b`, headerType: "list", pos: 2, want: stheader.ErrInvalidCharacter},
+		{input: `a, ,b`, headerType: "list", pos: 3, want: stheader.ErrInvalidCharacter},
+		{input: `a,`, headerType: "list", pos: 2, want: stheader.ErrUnexpectedEOF},
+		{input: `,a=1`, headerType: "dictionary", pos: 0, want: stheader.ErrInvalidCharacter},
+		{input: `a=1,,b=2`, headerType: "dictionary", pos: 4, want: stheader.ErrInvalidCharacter},
+		{input: `a=1, ,b=2`, headerType: "dictionary", pos: 5, want: stheader.ErrInvalidCharacter},
+		{input: `a=1,`, headerType: "dictionary", pos: 4, want: stheader.ErrUnexpectedEOF},
+	}
+	for _, c := range testCases {
+		_, err := parse(c.headerType, c.input)
+		var perr *stheader.ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("should fail with ParseError for %q, got=%v", c.input, err)
+			continue
+		}
+		if !errors.Is(err, c.want) {
+			t.Errorf("error mismatch for %q, got=%v, want=%v", c.input, err, c.want)
+		}
+		if got := perr.Pos(); got != c.pos {
+			t.Errorf("error position mismatch for %q, got=%d, want=%d", c.input, got, c.pos)
+		}
+	}
+}
+
+func TestParseIntegerSign(t *testing.T) {
+	testCases := []struct {
+		input              string
+		rejectLeadingZeros bool
+		want               int64
+		wantErr            bool
+		wantErrPos         int
+	}{
+		{input: "-", wantErr: true, wantErrPos: 1},
+		{input: "--5", wantErr: true, wantErrPos: 1},
+		{input: "-a", wantErr: true, wantErrPos: 1},
+		{input: "007", want: 7},
+		{input: "007", rejectLeadingZeros: true, wantErr: true, wantErrPos: 0},
+		{input: "-007", rejectLeadingZeros: true, wantErr: true, wantErrPos: 1},
+		{input: "00.5", rejectLeadingZeros: true, wantErr: true, wantErrPos: 0},
+		{input: "0", rejectLeadingZeros: true, want: 0},
+		{input: "-0", want: 0},
+		{input: "-0", rejectLeadingZeros: true, want: 0},
+	}
+	for _, c := range testCases {
+		p := stheader.NewParser(c.input)
+		p.RejectLeadingZeros = c.rejectLeadingZeros
+		item, err := p.ParseItem()
+		if c.wantErr {
+			var perr *stheader.ParseError
+			if !errors.As(err, &perr) {
+				t.Errorf("should fail with ParseError for %q, got=%v", c.input, err)
+			} else if got := perr.Pos(); got != c.wantErrPos {
+				t.Errorf("error position mismatch for %q, got=%d, want=%d", c.input, got, c.wantErrPos)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", c.input, err)
+			continue
+		}
+		if got := item.BareItem().AsInt(); got != c.want {
+			t.Errorf("value mismatch for %q, got=%d, want=%d", c.input, got, c.want)
+		}
+	}
+}
+
+func TestParseNumberGrammar(t *testing.T) {
+	testCases := []struct {
+		input      string
+		wantErrPos int
+		wantErr    error
+	}{
+		{input: "1.", wantErrPos: 2, wantErr: stheader.ErrUnexpectedEOF},
+		{input: "1.a", wantErrPos: 2, wantErr: stheader.ErrInvalidCharacter},
+		{input: "1..5", wantErrPos: 2, wantErr: stheader.ErrInvalidCharacter},
+		{input: "-.5", wantErrPos: 1, wantErr: stheader.ErrInvalidCharacter},
+		{input: "1.1234", wantErrPos: 5, wantErr: stheader.ErrInvalidCharacter},
+		{input: "-1.123456", wantErrPos: 6, wantErr: stheader.ErrInvalidCharacter},
+	}
+	for _, c := range testCases {
+		_, err := stheader.NewParser(c.input).ParseItem()
+		var perr *stheader.ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("should fail with ParseError for %q, got=%v", c.input, err)
+			continue
+		}
+		if got := perr.Pos(); got != c.wantErrPos {
+			t.Errorf("error position mismatch for %q, got=%d, want=%d", c.input, got, c.wantErrPos)
+		}
+		if !errors.Is(err, c.wantErr) {
+			t.Errorf("error mismatch for %q, got=%v, want=%v", c.input, err, c.wantErr)
+		}
+	}
+
+	for _, input := range []string{"1.1", "-1.12", "1.123", "0.001"} {
+		if _, err := stheader.NewParser(input).ParseItem(); err != nil {
+			t.Errorf("unexpected error for %q: %s", input, err)
+		}
+	}
+}
+
+func TestParseRoundFractionalDigits(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  string
+	}{
+		{input: "1.2345", want: "1.234"},
+		{input: "1.2355", want: "1.236"},
+		{input: "1.0005", want: "1.0"},
+		{input: "1.00051", want: "1.001"},
+		{input: "-1.23456", want: "-1.235"},
+		{input: "1.234567", want: "1.235"},
+		{input: "0.999500", want: "1.0"},
+	}
+	for _, c := range testCases {
+		if _, err := stheader.NewParser(c.input).ParseItem(); err == nil {
+			t.Errorf("should fail for %q by default", c.input)
+		}
+
+		p := stheader.NewParser(c.input)
+		p.RoundFractionalDigits = true
+		item, err := p.ParseItem()
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", c.input, err)
+			continue
+		}
+		got, err := stheader.Serialize(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("result mismatch for %q, got=%q, want=%q", c.input, got, c.want)
+		}
+
+		item2, err := stheader.NewParser(got).ParseItem()
+		if err != nil {
+			t.Fatalf("reparse %q: %s", got, err)
+		}
+		if !stheader.EqualItem(item, item2) {
+			t.Errorf("round trip mismatch for %q, got=%v, want=%v", c.input, item2, item)
+		}
+	}
+}
+
+func TestParseLenientKeys(t *testing.T) {
+	const input = `Foo=1;Bar, baz=2`
+	if _, err := stheader.NewParser(input).ParseDictionary(); err == nil {
+		t.Errorf("should fail for %q in strict mode", input)
+	}
+
+	p := stheader.NewParser(input)
+	p.LenientKeys = true
+	dict, err := p.ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := stheader.Serialize(dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `foo=1;bar, baz=2`; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+
+	p = stheader.NewParser(`Foo=1, foo=2`)
+	p.LenientKeys = true
+	if _, err := p.ParseDictionary(); !errors.Is(err, stheader.ErrDuplicateKey) {
+		t.Errorf("should fail with duplicate key, got=%v", err)
+	}
+}
+
+func TestParseEOFMessage(t *testing.T) {
+	testCases := []struct {
+		input      string
+		headerType string
+		want       string
+	}{
+		{input: `a=`, headerType: "dictionary", want: "unexpected end of input while parsing dictionary"},
+		{input: `a=1, b;`, headerType: "dictionary", want: "unexpected end of input while parsing parameters"},
+		{input: `a=(1 2`, headerType: "dictionary", want: "unexpected end of input while parsing inner list"},
+		{input: `(1 2`, headerType: "list", want: "unexpected end of input while parsing inner list"},
+		{input: `1;`, headerType: "item", want: "unexpected end of input while parsing parameters"},
+		{input: `1;a=`, headerType: "item", want: "unexpected end of input while parsing parameters"},
+		{input: `(1);a=`, headerType: "list", want: "unexpected end of input while parsing parameters"},
+		{input: `"abc`, headerType: "item", want: "unexpected end of input while parsing string"},
+		{input: `?`, headerType: "item", want: "unexpected end of input while parsing boolean"},
+	}
+	for _, c := range testCases {
+		_, err := parse(c.headerType, c.input)
+		if !errors.Is(err, stheader.ErrUnexpectedEOF) {
+			t.Errorf("should fail with ErrUnexpectedEOF for %q, got=%v", c.input, err)
+			continue
+		}
+		if got := err.Error(); !strings.Contains(got, c.want) {
+			t.Errorf("error message mismatch for %q, got=%q, want=%q", c.input, got, c.want)
+		}
+	}
+}
+
+func TestParseByteSeqInvalid(t *testing.T) {
+	testCases := []struct {
+		input   string
+		errPos  int
+		errKind error
+	}{
+		{input: "*!!!*", errPos: 1, errKind: stheader.ErrInvalidCharacter},
+		{input: "*aGk aGk=*", errPos: 4, errKind: stheader.ErrInvalidCharacter},
+		{input: `*aGk\=*`, errPos: 4, errKind: stheader.ErrInvalidCharacter},
+		{input: "*aGk=", errPos: 5, errKind: stheader.ErrUnexpectedEOF},
+		{input: "*", errPos: 1, errKind: stheader.ErrUnexpectedEOF},
+	}
+	for _, c := range testCases {
+		_, err := stheader.NewParser(c.input).ParseItem()
+		var perr *stheader.ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("should fail with ParseError for %q, got=%v", c.input, err)
+			continue
+		}
+		if got, want := perr.Pos(), c.errPos; got != want {
+			t.Errorf("error position mismatch for %q, got=%d, want=%d", c.input, got, want)
+		}
+		if !errors.Is(err, c.errKind) {
+			t.Errorf("error mismatch for %q, got=%v, want=%v", c.input, err, c.errKind)
+		}
+	}
+
+	item, err := stheader.NewParser("**").ParseItem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := item.BareItem().AsByteSeq(); got == nil || len(got) != 0 {
+		t.Errorf("should be an empty non-nil byte sequence, got=%#v", got)
+	}
+}
+
+func TestParseDuplicateParameter(t *testing.T) {
+	testCases := []struct {
+		input      string
+		headerType string
+		wantPos    int
+	}{
+		{input: `(1 2);a=1;a=2`, headerType: "list", wantPos: 10},
+		{input: `x, (1 2);a;b;a`, headerType: "list", wantPos: 13},
+		{input: `k=(1 2);a=1;a=2`, headerType: "dictionary", wantPos: 12},
+		{input: `(1;a=1;a=2 2)`, headerType: "list", wantPos: 7},
+		{input: `1;a=1; a=2`, headerType: "item", wantPos: 7},
+		{input: `a=1, b=2, a=3`, headerType: "dictionary", wantPos: 10},
+	}
+	for _, c := range testCases {
+		_, err := parse(c.headerType, c.input)
+		if !errors.Is(err, stheader.ErrDuplicateKey) {
+			t.Errorf("should fail with ErrDuplicateKey for %q, got=%v", c.input, err)
+			continue
+		}
+		var perr *stheader.ParseError
+		if errors.As(err, &perr) && perr.Pos() != c.wantPos {
+			t.Errorf("error position mismatch for %q, got=%d, want=%d", c.input, perr.Pos(), c.wantPos)
+		}
+	}
+}
+
+func TestParseDictionaryBooleanTrue(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  string
+	}{
+		{input: `a, b=2`, want: `a, b=2`},
+		{input: `a=?1, b=2, c`, want: `a, b=2, c`},
+		{input: `a=?0`, want: `a=?0`},
+	}
+	for _, c := range testCases {
+		dict, err := stheader.NewParser(c.input).ParseDictionary()
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", c.input, err)
+			continue
+		}
+		m, ok := dict.Load("a")
+		if !ok {
+			t.Fatalf("key a not found for %q", c.input)
+		}
+		if _, ok := m.AsItem().BareItem().BoolValue(); !ok {
+			t.Errorf("value of a should be boolean for %q", c.input)
+		}
+		got, err := stheader.Serialize(dict)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("serialize mismatch for %q, got=%q, want=%q", c.input, got, c.want)
+		}
+	}
+}
+
+func TestParseParameterBooleanTrue(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  string
+	}{
+		{input: `1;a;b=2`, want: `1;a;b=2`},
+		{input: `1;a=?1;b=?0`, want: `1;a;b=?0`},
+		{input: `(1 2);a;b=2`, want: `(1 2);a;b=2`},
+	}
+	for _, c := range testCases {
+		list, err := stheader.NewParser(c.input).ParseList()
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", c.input, err)
+			continue
+		}
+		var params stheader.Parameters
+		if list[0].Type() == stheader.MemberTypeItem {
+			params = list[0].AsItem().Parameters()
+		} else {
+			params = list[0].AsInnerList().Parameters()
+		}
+		if v, ok := stheader.ParamValue[bool](params, "a"); !ok || !v {
+			t.Errorf("parameter a should be boolean true for %q", c.input)
+		}
+		got, err := stheader.Serialize(list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("serialize mismatch for %q, got=%q, want=%q", c.input, got, c.want)
+		}
+	}
+}
+
+func TestParseParameterNotNil(t *testing.T) {
+	list, err := stheader.NewParser(`(1;a 2);b, c;d;e=?0`).ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(params stheader.Parameters) {
+		params.Range(func(name string, value stheader.BareItem) bool {
+			if value == nil {
+				t.Errorf("parameter %s should not be nil", name)
+			} else if value.Type() != stheader.ItemTypeBool {
+				t.Errorf("parameter %s type mismatch, got=%s, want=%s", name, value.Type(), stheader.ItemTypeBool)
+			}
+			return true
+		})
+	}
+	check(list[0].AsInnerList().Items()[0].Parameters())
+	check(list[0].AsInnerList().Parameters())
+	check(list[1].AsItem().Parameters())
+
+	b, err := json.Marshal(list[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `[{"__type":"token","value":"c"},[["d",true],["e",false]]]`; got != want {
+		t.Errorf("JSON mismatch, got=%s, want=%s", got, want)
+	}
+}
+
+func TestParseIntegerOutOfRange(t *testing.T) {
+	testCases := []struct {
+		input  string
+		limit  int
+		errPos int
+	}{
+		{input: "1000000000000000", errPos: 15},
+		{input: "-1000000000000000", errPos: 16},
+		{input: "9223372036854775808", limit: 20, errPos: 0},
+		{input: "-9223372036854775809", limit: 20, errPos: 0},
+	}
+	for _, c := range testCases {
+		p := stheader.NewParser(c.input)
+		p.IntegerDigitLimit = c.limit
+		_, err := p.ParseItem()
+		var perr *stheader.ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("should fail with ParseError for %q, got=%v", c.input, err)
+			continue
+		}
+		if !strings.Contains(perr.Error(), "integer out of range") {
+			t.Errorf("error message mismatch for %q, got=%q", c.input, perr.Error())
+		}
+		if got := perr.Pos(); got != c.errPos {
+			t.Errorf("error position mismatch for %q, got=%d, want=%d", c.input, got, c.errPos)
+		}
+	}
+
+	p := stheader.NewParser("-9223372036854775808")
+	p.IntegerDigitLimit = 19
+	item, err := p.ParseItem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := item.BareItem().AsInt(), int64(math.MinInt64); got != want {
+		t.Errorf("value mismatch, got=%d, want=%d", got, want)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	list := strings.Repeat("a, ", 99) + "a"
+
+	p := stheader.NewParser(list)
+	p.MaxInputLen = len(list) - 1
+	if _, err := p.ParseList(); !errors.Is(err, stheader.ErrTooLarge) {
+		t.Errorf("should fail with ErrTooLarge for too long input, got=%v", err)
+	}
+	p.Reset(list)
+	p.MaxInputLen = len(list)
+	if _, err := p.ParseList(); err != nil {
+		t.Errorf("unexpected error for input within the length limit: %s", err)
+	}
+
+	p = stheader.NewParser(list)
+	p.MaxListMembers = 10
+	_, err := p.ParseList()
+	var perr *stheader.ParseError
+	if !errors.Is(err, stheader.ErrTooLarge) || !errors.As(err, &perr) {
+		t.Fatalf("should fail with ErrTooLarge for too many list members, got=%v", err)
+	}
+	if got, want := perr.Pos(), 30; got != want {
+		t.Errorf("error position mismatch, got=%d, want=%d", got, want)
+	}
+	p.Reset(list)
+	p.MaxListMembers = 100
+	if _, err := p.ParseList(); err != nil {
+		t.Errorf("unexpected error for list within the member limit: %s", err)
+	}
+
+	p = stheader.NewParser(`a=1, b=2, c=3`)
+	p.MaxDictEntries = 2
+	if _, err := p.ParseDictionary(); !errors.Is(err, stheader.ErrTooLarge) {
+		t.Errorf("should fail with ErrTooLarge for too many dictionary members, got=%v", err)
+	}
+	p.Reset(`a=1, b=2`)
+	if _, err := p.ParseDictionary(); err != nil {
+		t.Errorf("unexpected error for dictionary within the member limit: %s", err)
+	}
+}
+
+func TestParseInnerListLimit(t *testing.T) {
+	input := "(" + strings.Repeat("a ", 1024) + "a)"
+	_, err := stheader.NewParser(input).ParseList()
+	var perr *stheader.ParseError
+	if !errors.Is(err, stheader.ErrTooComplex) || !errors.As(err, &perr) {
+		t.Fatalf("should fail with ErrTooComplex, got=%v", err)
+	}
+	if got, want := perr.Pos(), 1+2*1024; got != want {
+		t.Errorf("error position mismatch, got=%d, want=%d", got, want)
+	}
+
+	p := stheader.NewParser(input)
+	p.MaxInnerListItems = -1
+	if _, err := p.ParseList(); err != nil {
+		t.Errorf("unexpected error without the limit: %s", err)
+	}
+
+	p.Reset("(a b c)")
+	p.MaxInnerListItems = 2
+	if _, err := p.ParseList(); !errors.Is(err, stheader.ErrTooComplex) {
+		t.Errorf("should fail with ErrTooComplex for the custom limit, got=%v", err)
+	}
+	p.Reset("(a b)")
+	if _, err := p.ParseList(); err != nil {
+		t.Errorf("unexpected error within the custom limit: %s", err)
+	}
+}
+
+// The regular expressions which the parser used before the token and
+// key scanners were written by hand. They are kept to verify the
+// scanners accept the same strings and to compare the performance.
+var (
+	tokenRegexp = regexp.MustCompile(`^[a-zA-Z\*][a-zA-Z0-9_\-\.\:\%\*\/]*`)
+	keyRegexp   = regexp.MustCompile(`^[a-z][a-z0-9_\-\*]{0,254}`)
+)
+
+func validTokenRegexp(s string) bool {
+	m := tokenRegexp.FindStringIndex(s)
+	return len(m) != 0 && m[1] == len(s)
+}
+
+func validKeyRegexp(s string) bool {
+	m := keyRegexp.FindStringIndex(s)
+	return len(m) != 0 && m[1] == len(s)
+}
+
+func TestValidTokenAndKeyMatchRegexp(t *testing.T) {
+	inputs := []string{"", strings.Repeat("a", 255), strings.Repeat("a", 256)}
+	for c1 := 0; c1 < 256; c1++ {
+		inputs = append(inputs, string([]byte{byte(c1)}))
+		for c2 := 0; c2 < 256; c2++ {
+			inputs = append(inputs, string([]byte{'a', byte(c1), byte(c2)}))
+		}
+	}
+	for _, input := range inputs {
+		if got, want := stheader.ValidToken(input), validTokenRegexp(input); got != want {
+			t.Errorf("ValidToken mismatch for %q, got=%v, want=%v", input, got, want)
+		}
+		if got, want := stheader.ValidKey(input), validKeyRegexp(input); got != want {
+			t.Errorf("ValidKey mismatch for %q, got=%v, want=%v", input, got, want)
+		}
+	}
+}
+
+var benchmarkTokensAndKeys = strings.Fields(`max-age must-revalidate text/html
+	application/json foo*bar sha-256 gzip br a x-forwarded-for *`)
+
+func BenchmarkValidTokenManual(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, s := range benchmarkTokensAndKeys {
+			stheader.ValidToken(s)
+		}
+	}
+}
+
+func BenchmarkValidTokenRegexp(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, s := range benchmarkTokensAndKeys {
+			validTokenRegexp(s)
+		}
+	}
+}
+
+func BenchmarkValidKeyManual(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, s := range benchmarkTokensAndKeys {
+			stheader.ValidKey(s)
+		}
+	}
+}
+
+func BenchmarkValidKeyRegexp(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, s := range benchmarkTokensAndKeys {
+			validKeyRegexp(s)
+		}
+	}
+}
+
+// benchmarkByteSeqDictionary returns a dictionary of 20 "Byte Sequence"
+// values.
+func benchmarkByteSeqDictionary() string {
+	var sb strings.Builder
+	for i := 0; i < 20; i++ {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "k%d=*%s*", i, base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", 10+i))))
+	}
+	return sb.String()
+}
+
+// benchmarkRealisticDictionary is a dictionary of 10 keys with
+// values of mixed types, some of which have parameters.
+const benchmarkRealisticDictionary = `max-age=3600, private, ` +
+	`lang="en-US";q=0.9, ver=2.5, id=*aGVsbG8gd29ybGQ=*, ` +
+	`enc=(gzip br;q=0.5 deflate);default=gzip, ` +
+	`ts=@1659578233, mode=strict;level=3, retry=?0, ` +
+	`title=%"caf%c3%a9"`
+
+// benchmarkTokenList is a list of plain tokens like the Vary header.
+const benchmarkTokenList = `accept, accept-encoding, accept-language, ` +
+	`authorization, cookie, origin, user-agent, x-requested-with`
+
+func BenchmarkParseRealisticDictionary(b *testing.B) {
+	input := []byte(benchmarkRealisticDictionary)
+	b.ReportAllocs()
+	p := stheader.NewParserBytes(nil)
+	for i := 0; i < b.N; i++ {
+		p.ResetBytes(input)
+		if _, err := p.ParseDictionary(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseTokenList(b *testing.B) {
+	input := []byte(benchmarkTokenList)
+	b.ReportAllocs()
+	p := stheader.NewParserBytes(nil)
+	for i := 0; i < b.N; i++ {
+		p.ResetBytes(input)
+		if _, err := p.ParseList(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseByteSeqDictionary(b *testing.B) {
+	input := []byte(benchmarkByteSeqDictionary())
+	b.ReportAllocs()
+	p := stheader.NewParserBytes(nil)
+	for i := 0; i < b.N; i++ {
+		p.ResetBytes(input)
+		if _, err := p.ParseDictionary(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParseErrorIsParseError(t *testing.T) {
+	testCases := []struct {
+		input      string
+		headerType string
+		pos        int
+	}{
+		{input: `"abc`, headerType: "item", pos: 4},
+		{input: `*a*`, headerType: "item", pos: 2},
+		{input: `*aGk=`, headerType: "item", pos: 5},
+		{input: `*a=Gk=*`, headerType: "item", pos: 2},
+		{input: `1 2`, headerType: "item", pos: 2},
+		{input: `1234567890123456`, headerType: "item", pos: 15},
+		{input: `1234567890123.5`, headerType: "item", pos: 12},
+		{input: `1.2345`, headerType: "item", pos: 5},
+		{input: `?2`, headerType: "item", pos: 1},
+		{input: `@x`, headerType: "item", pos: 1},
+		{input: `%"a`, headerType: "item", pos: 3},
+		{input: `(1 2`, headerType: "list", pos: 4},
+		{input: `a;`, headerType: "list", pos: 2},
+		{input: `a=`, headerType: "dictionary", pos: 2},
+		{input: `a=1, a=2`, headerType: "dictionary", pos: 5},
+		{input: `a=1;b=2;b=3`, headerType: "dictionary", pos: 8},
+	}
+	for _, c := range testCases {
+		_, err := parse(c.headerType, c.input)
+		var perr *stheader.ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("should fail with ParseError for %q, got=%v", c.input, err)
+			continue
+		}
+		if got := perr.Pos(); got != c.pos {
+			t.Errorf("error position mismatch for %q, got=%d, want=%d (%v)", c.input, got, c.pos, err)
+		}
+	}
+}
+
+func TestParseErrorUnwrapNumError(t *testing.T) {
+	testCases := []struct {
+		input  string
+		digits int
+		want   error
+		kind   error
+	}{
+		{input: `1234567890123456`, want: strconv.ErrRange},
+		{input: `9999999999999999999`, digits: 19, want: strconv.ErrRange},
+		{input: `99999999999999999999`, digits: 20, want: strconv.ErrRange},
+		{input: `1234567890123.5`, want: strconv.ErrRange},
+		{input: `-`, want: strconv.ErrSyntax, kind: stheader.ErrUnexpectedEOF},
+		{input: `-a`, want: strconv.ErrSyntax, kind: stheader.ErrInvalidCharacter},
+		{input: `1.a`, want: strconv.ErrSyntax, kind: stheader.ErrInvalidCharacter},
+	}
+	for _, c := range testCases {
+		p := stheader.NewParser(c.input)
+		p.IntegerDigitLimit = c.digits
+		_, err := p.ParseItem()
+		if !errors.Is(err, c.want) {
+			t.Errorf("error mismatch for %q, got=%v, want=%v", c.input, err, c.want)
+		}
+		var numErr *strconv.NumError
+		if !errors.As(err, &numErr) {
+			t.Errorf("should wrap *strconv.NumError for %q, got=%v", c.input, err)
+		}
+		if c.kind != nil && !errors.Is(err, c.kind) {
+			t.Errorf("error kind mismatch for %q, got=%v, want=%v", c.input, err, c.kind)
+		}
+	}
+}
+
+func TestParseErrorGroup(t *testing.T) {
+	// The cases are in the format of the HTTPWG tests so that they
+	// do not depend on the test suite having an error group.
+	const tests = `[
+		{"name": "empty item", "raw": [""], "header_type": "item", "must_fail": true},
+		{"name": "unterminated string", "raw": ["\"abc"], "header_type": "item", "must_fail": true},
+		{"name": "invalid boolean", "raw": ["?2"], "header_type": "item", "must_fail": true},
+		{"name": "decimal without fraction", "raw": ["1."], "header_type": "item", "must_fail": true},
+		{"name": "too long integer", "raw": ["1234567890123456"], "header_type": "item", "must_fail": true},
+		{"name": "unterminated byte sequence", "raw": ["*aGk"], "header_type": "item", "must_fail": true},
+		{"name": "empty parameter key", "raw": ["a;"], "header_type": "item", "must_fail": true},
+		{"name": "trailing data", "raw": ["a b"], "header_type": "item", "must_fail": true},
+		{"name": "trailing comma", "raw": ["1, "], "header_type": "list", "must_fail": true},
+		{"name": "empty list member", "raw": ["1,,2"], "header_type": "list", "must_fail": true},
+		{"name": "unterminated inner list", "raw": ["(1 2"], "header_type": "list", "must_fail": true},
+		{"name": "inner list without separator", "raw": ["(1\"a\")"], "header_type": "list", "must_fail": true},
+		{"name": "uppercase key", "raw": ["A=1"], "header_type": "dictionary", "must_fail": true},
+		{"name": "double equals", "raw": ["a==1"], "header_type": "dictionary", "must_fail": true},
+		{"name": "trailing comma in dictionary", "raw": ["a=1,"], "header_type": "dictionary", "must_fail": true}
+	]`
+	group, err := readHTTPWGTestGroup(strings.NewReader(tests))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range group {
+		t.Run(test.Name, func(t *testing.T) {
+			input := strings.Join(test.Raw, ",")
+			_, err := parse(test.HeaderType, input)
+			if err == nil {
+				if test.MustFail {
+					t.Error("should have failed")
+				}
+				return
+			}
+			var perr *stheader.ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("error should be *ParseError, got=%T(%v)", err, err)
+			}
+			if pos := perr.Pos(); pos < 0 || pos > len(input) {
+				t.Errorf("error position out of input, pos=%d, len=%d", pos, len(input))
+			}
+		})
+	}
+}
+
+func TestParseDictionaryBooleanTrueWithParams(t *testing.T) {
+	// The cases are in the format of the HTTPWG tests.
+	const tests = `[
+		{
+			"name": "boolean true with a parameter",
+			"raw": ["a;x=1"],
+			"header_type": "dictionary",
+			"expected": {"a": [true, {"x": 1}]}
+		},
+		{
+			"name": "boolean true with parameters followed by false",
+			"raw": ["a;x=1;y, b=?0"],
+			"header_type": "dictionary",
+			"expected": {"a": [true, {"x": 1, "y": true}], "b": [false, {}]}
+		},
+		{
+			"name": "explicit boolean true with a parameter",
+			"raw": ["a=?1;x=\"s\""],
+			"header_type": "dictionary",
+			"expected": {"a": [true, {"x": "s"}]},
+			"canonical": ["a;x=\"s\""]
+		},
+		{
+			"name": "boolean false with a parameter",
+			"raw": ["a=?0;x=1"],
+			"header_type": "dictionary",
+			"expected": {"a": [false, {"x": 1}]}
+		}
+	]`
+	group, err := readHTTPWGTestGroup(strings.NewReader(tests))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range group {
+		t.Run(test.Name, func(t *testing.T) {
+			input := strings.Join(test.Raw, ",")
+			dict, err := stheader.NewParser(input).ParseDictionary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := convertDictionaryToExpected(dict); !reflect.DeepEqual(got, test.Expected) {
+				t.Errorf("unmatch result, got=%+v, want=%+v", got, test.Expected)
+			}
+			want := input
+			if len(test.Canonical) > 0 {
+				want = strings.Join(test.Canonical, ", ")
+			}
+			if got, err := stheader.Serialize(dict); err != nil {
+				t.Fatal(err)
+			} else if got != want {
+				t.Errorf("serialize mismatch, got=%q, want=%q", got, want)
+			}
+		})
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	testCases := []struct {
+		input      string
+		headerType string
+		want       string
+		wantTail   string
+		wantErr    bool
+	}{
+		{input: `a, b extra`, headerType: "list", want: `a, b`, wantTail: `extra`},
+		{input: `a, (b c);x=1 ; rest`, headerType: "list", want: `a, (b c);x=1`, wantTail: `; rest`},
+		{input: `a, b`, headerType: "list", want: `a, b`, wantTail: ``},
+		{input: `a, "b`, headerType: "list", wantErr: true},
+		{input: `a, b,`, headerType: "list", wantErr: true},
+		{input: `a=1, b extra`, headerType: "dictionary", want: `a=1, b`, wantTail: `extra`},
+		{input: `a=1 b=2`, headerType: "dictionary", want: `a=1`, wantTail: `b=2`},
+		{input: `"foo";x=1 bar`, headerType: "item", want: `"foo";x=1`, wantTail: `bar`},
+		{input: `1, 2`, headerType: "item", want: `1`, wantTail: `, 2`},
+	}
+	for _, c := range testCases {
+		p := stheader.NewParser(c.input)
+		var v interface{}
+		var tail string
+		var err error
+		switch c.headerType {
+		case "list":
+			v, tail, err = p.ParseListPrefix()
+		case "dictionary":
+			v, tail, err = p.ParseDictionaryPrefix()
+		default:
+			v, tail, err = p.ParseItemPrefix()
+		}
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("should fail for %q", c.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", c.input, err)
+			continue
+		}
+		if got := fmt.Sprint(v); got != c.want {
+			t.Errorf("result mismatch for %q, got=%q, want=%q", c.input, got, c.want)
+		}
+		if tail != c.wantTail {
+			t.Errorf("tail mismatch for %q, got=%q, want=%q", c.input, tail, c.wantTail)
+		}
+	}
+
+	// The strict mode is not affected after parsing a prefix.
+	p := stheader.NewParser(`a b`)
+	if _, _, err := p.ParseListPrefix(); err != nil {
+		t.Fatal(err)
+	}
+	p.Reset(`a b`)
+	if _, err := p.ParseList(); err == nil {
+		t.Error("ParseList should fail for trailing data")
+	}
+}
+
+func TestParserPosAndRemaining(t *testing.T) {
+	const input = `  a;x=1, (b c) ; d=2`
+	p := stheader.NewParser(input)
+	if got, want := p.Pos(), 2; got != want {
+		t.Errorf("initial position mismatch, got=%d, want=%d", got, want)
+	}
+	if got, want := p.Remaining(), input[2:]; got != want {
+		t.Errorf("initial remaining mismatch, got=%q, want=%q", got, want)
+	}
+
+	if _, _, err := p.ParseListPrefix(); err != nil {
+		t.Fatal(err)
+	}
+	wantPos := strings.Index(input, "; d")
+	// Calling twice checks that they do not advance the parser.
+	for i := 0; i < 2; i++ {
+		if got := p.Pos(); got != wantPos {
+			t.Errorf("position mismatch, got=%d, want=%d", got, wantPos)
+		}
+		if got, want := p.Remaining(), "; d=2"; got != want {
+			t.Errorf("remaining mismatch, got=%q, want=%q", got, want)
+		}
+	}
+
+	p.Reset(`1`)
+	if _, err := p.ParseItem(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.Pos(), 1; got != want {
+		t.Errorf("position mismatch at end, got=%d, want=%d", got, want)
+	}
+	if got := p.Remaining(); got != "" {
+		t.Errorf("remaining should be empty at end, got=%q", got)
+	}
+}
+
+func TestParseParameters(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{input: ``, want: []string{}},
+		{input: `;a=1`, want: []string{"a"}},
+		{input: ` ;a=1;b;c="s" `, want: []string{"a", "b", "c"}},
+		{input: `;a=1;a=2`, wantErr: true},
+		{input: `;a=1 junk`, wantErr: true},
+		{input: `a=1`, wantErr: true},
+	}
+	for _, c := range testCases {
+		params, err := stheader.NewParser(c.input).ParseParameters()
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("should fail for %q", c.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", c.input, err)
+			continue
+		}
+		if got := params.Keys(); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("keys mismatch for %q, got=%q, want=%q", c.input, got, c.want)
+		}
+	}
+
+	_, err := stheader.NewParser(`;a=1;a=2`).ParseParameters()
+	if !errors.Is(err, stheader.ErrDuplicateKey) {
+		t.Errorf("should fail with ErrDuplicateKey, got=%v", err)
+	}
+}
+
+func TestParseBareItem(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  stheader.BareItem
+	}{
+		{input: `"foo"`, want: stheader.NewBareItem("foo")},
+		{input: `*aGk=*`, want: stheader.NewBareItem([]byte("hi"))},
+		{input: `?1`, want: stheader.NewBareItem(true)},
+		{input: `-42`, want: stheader.NewBareItem(int64(-42))},
+		{input: `1.5`, want: stheader.NewBareItem(stheader.NewDecimal(1500))},
+		{input: `foo/bar`, want: stheader.NewBareItem(stheader.Token("foo/bar"))},
+		{input: `@1659578233`, want: stheader.NewBareItem(time.Unix(1659578233, 0))},
+		{input: `%"f%c3%bc"`, want: stheader.NewBareItem(stheader.DisplayString("fü"))},
+	}
+	for _, c := range testCases {
+		got, err := stheader.NewParser(c.input).ParseBareItem()
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", c.input, err)
+			continue
+		}
+		if !stheader.EqualBareItem(got, c.want) {
+			t.Errorf("result mismatch for %q, got=%v, want=%v", c.input, got, c.want)
+		}
+	}
+
+	for _, input := range []string{``, `1;a=2`, `1 2`, `"foo" x`} {
+		if _, err := stheader.NewParser(input).ParseBareItem(); err == nil {
+			t.Errorf("should fail for %q", input)
+		}
+	}
+}
@@ -1,75 +1,334 @@
 package stheader
 
 import (
+	"encoding/base32"
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"io"
+	"math"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+// Serializer serializes Dictionary, List and Item to strings.
+// The zero value is ready to use.
+type Serializer struct {
+	// RFC8941 makes the serializer emit "Byte Sequence" values
+	// delimited by colons as defined in RFC 8941 instead of
+	// asterisks of draft-ietf-httpbis-header-structure-14.
+	// It also allows "Token" values starting with "*", which are
+	// read back as tokens only by a Parser with RFC8941 set.
+	RFC8941 bool
+
+	// IntegerDigitLimit is the maximum number of digits of "Integer"
+	// values. Zero means the default limit of 15 digits.
+	IntegerDigitLimit int
+
+	// Compact makes the serializer emit "," instead of ", " between
+	// members of List and Dictionary. The output is valid but not
+	// in the canonical form.
+	Compact bool
+
+	// ByteSeqEncoding is the encoding of "Byte Sequence" values.
+	// The default is base64 as the specification defines.
+	ByteSeqEncoding ByteSeqEncoding
+}
+
+// SerializerOption is an option for NewSerializer.
+type SerializerOption func(*Serializer)
+
+// WithRFC8941 makes the serializer emit "Byte Sequence" values
+// delimited by colons as defined in RFC 8941.
+func WithRFC8941() SerializerOption {
+	return func(s *Serializer) {
+		s.RFC8941 = true
+	}
+}
+
+// WithIntegerDigitLimit sets the maximum number of digits of "Integer"
+// values.
+func WithIntegerDigitLimit(limit int) SerializerOption {
+	return func(s *Serializer) {
+		s.IntegerDigitLimit = limit
+	}
+}
+
+// WithCompact makes the serializer emit "," instead of ", " between
+// members of List and Dictionary.
+func WithCompact() SerializerOption {
+	return func(s *Serializer) {
+		s.Compact = true
+	}
+}
+
+// WithByteSeqEncoding sets the encoding of "Byte Sequence" values.
+func WithByteSeqEncoding(enc ByteSeqEncoding) SerializerOption {
+	return func(s *Serializer) {
+		s.ByteSeqEncoding = enc
+	}
+}
+
+// NewSerializer creates a new Serializer with options.
+// Without options, it is the same as the zero value of Serializer
+// and emits the canonical form.
+func NewSerializer(opts ...SerializerOption) *Serializer {
+	s := &Serializer{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// memberSeparator returns the separator between members of List and
+// Dictionary.
+func (s *Serializer) memberSeparator() string {
+	if s.Compact {
+		return ","
+	}
+	return ", "
+}
+
+const defaultIntegerDigitLimit = 15
+
+func integerDigitLimit(limit int) int {
+	if limit <= 0 {
+		return defaultIntegerDigitLimit
+	}
+	return limit
+}
+
 // Serialize return an ASCII string suitable for use in a HTTP header value.
-// It panics if value is neither Dictionary, List nor Item.
+// If value implements Marshaler, the value returned by its
+// MarshalStructuredHeader method is serialized instead.
+// It returns an error if value is neither Dictionary, List, Item,
+// Member nor BareItem.
 func Serialize(value interface{}) (string, error) {
+	var s Serializer
+	return s.Serialize(value)
+}
+
+// Serialize return an ASCII string suitable for use in a HTTP header value.
+// If value implements Marshaler, the value returned by its
+// MarshalStructuredHeader method is serialized instead.
+// It returns an error if value is neither Dictionary, List, Item,
+// Member nor BareItem.
+func (s *Serializer) Serialize(value interface{}) (string, error) {
+	if m, ok := value.(Marshaler); ok {
+		var err error
+		value, err = m.MarshalStructuredHeader()
+		if err != nil {
+			return "", err
+		}
+	}
 	switch v := value.(type) {
 	case Dictionary:
-		return serializeDictionary(v)
+		return s.SerializeDictionary(v)
 	case List:
-		return serializeList(v)
+		return s.SerializeList(v)
 	case Item:
-		return serializeItem(v)
+		return s.SerializeItem(v)
+	case Member:
+		return s.SerializeMember(v)
+	case BareItem:
+		return s.SerializeBareItem(v)
 	default:
-		panic("invalid value type")
+		return "", errors.New("invalid value type")
 	}
 }
 
-func serializeDictionary(dict Dictionary) (string, error) {
+const invalidString = "<invalid>"
+
+// serializeString returns the serialized form of value for String
+// methods. It returns "<invalid>" if value cannot be serialized.
+func serializeString(value interface{}) (str string) {
+	defer func() {
+		if recover() != nil {
+			str = invalidString
+		}
+	}()
+	var s Serializer
 	var b []byte
-	b, err := appendDictionary(b, dict)
+	var err error
+	switch v := value.(type) {
+	case Dictionary:
+		b, err = s.appendDictionary(b, v)
+	case List:
+		b, err = s.appendList(b, v)
+	case Member:
+		b, err = s.appendMember(b, v)
+	case Item:
+		b, err = s.appendItem(b, v)
+	case InnerList:
+		b, err = s.appendInnerList(b, v)
+	case BareItem:
+		b, err = s.appendBareItem(b, v)
+	default:
+		return invalidString
+	}
+	if err != nil {
+		return invalidString
+	}
+	return string(b)
+}
+
+// SerializeDictionary serializes a Dictionary.
+// An empty or nil Dictionary is serialized to an empty string without
+// an error.
+func (s *Serializer) SerializeDictionary(dict Dictionary) (string, error) {
+	var b []byte
+	b, err := s.appendDictionary(b, dict)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// SerializeList serializes a List.
+// An empty or nil List is serialized to an empty string without
+// an error.
+func (s *Serializer) SerializeList(list List) (string, error) {
+	var b []byte
+	b, err := s.appendList(b, list)
 	if err != nil {
 		return "", err
 	}
 	return string(b), nil
 }
 
-func serializeList(list List) (string, error) {
+// SerializeItem serializes an Item.
+func (s *Serializer) SerializeItem(item Item) (string, error) {
 	var b []byte
-	b, err := appendList(b, list)
+	b, err := s.appendItem(b, item)
 	if err != nil {
 		return "", err
 	}
 	return string(b), nil
 }
 
-func serializeItem(item Item) (string, error) {
+// AppendDictionary appends the serialized Dictionary to b and returns
+// the extended buffer. On error, it returns b unchanged and the error.
+func (s *Serializer) AppendDictionary(b []byte, dict Dictionary) ([]byte, error) {
+	out, err := s.appendDictionary(b, dict)
+	if err != nil {
+		return b, err
+	}
+	return out, nil
+}
+
+// AppendList appends the serialized List to b and returns the extended
+// buffer. On error, it returns b unchanged and the error.
+func (s *Serializer) AppendList(b []byte, list List) ([]byte, error) {
+	out, err := s.appendList(b, list)
+	if err != nil {
+		return b, err
+	}
+	return out, nil
+}
+
+// AppendItem appends the serialized Item to b and returns the extended
+// buffer. On error, it returns b unchanged and the error.
+func (s *Serializer) AppendItem(b []byte, item Item) ([]byte, error) {
+	out, err := s.appendItem(b, item)
+	if err != nil {
+		return b, err
+	}
+	return out, nil
+}
+
+// WriteDictionary writes the serialized Dictionary to w and returns
+// the number of bytes written. The whole value is validated before
+// writing, so nothing is written if dict cannot be serialized.
+func (s *Serializer) WriteDictionary(w io.Writer, dict Dictionary) (int, error) {
+	b, err := s.appendDictionary(nil, dict)
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(b)
+}
+
+// WriteList writes the serialized List to w and returns the number
+// of bytes written. The whole value is validated before writing, so
+// nothing is written if list cannot be serialized.
+func (s *Serializer) WriteList(w io.Writer, list List) (int, error) {
+	b, err := s.appendList(nil, list)
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(b)
+}
+
+// WriteItem writes the serialized Item to w and returns the number
+// of bytes written. The whole value is validated before writing, so
+// nothing is written if item cannot be serialized.
+func (s *Serializer) WriteItem(w io.Writer, item Item) (int, error) {
+	b, err := s.appendItem(nil, item)
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(b)
+}
+
+// SerializeMember serializes a Member, that is, an Item or an
+// InnerList.
+func (s *Serializer) SerializeMember(m Member) (string, error) {
+	var b []byte
+	b, err := s.appendMember(b, m)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// SerializeBareItem serializes a BareItem with the zero value of
+// Serializer. It validates the value in the same way as in an Item.
+func SerializeBareItem(bi BareItem) (string, error) {
+	var s Serializer
+	return s.SerializeBareItem(bi)
+}
+
+// SerializeBareItem serializes a BareItem.
+// It validates the value in the same way as in an Item, for example,
+// it rejects invalid tokens and integers exceeding the digit limit.
+func (s *Serializer) SerializeBareItem(bi BareItem) (string, error) {
 	var b []byte
-	b, err := appendItem(b, item)
+	b, err := s.appendBareItem(b, bi)
 	if err != nil {
 		return "", err
 	}
 	return string(b), nil
 }
 
-func appendDictionary(b []byte, dict Dictionary) ([]byte, error) {
+func (s *Serializer) appendDictionary(b []byte, dict Dictionary) ([]byte, error) {
 	if dict == nil || dict.Len() == 0 {
 		return b, nil
 	}
+	d, ok := dict.(*dictionary)
+	if !ok {
+		return s.appendDictionaryRange(b, dict)
+	}
+	// Iterate directly to avoid allocating a closure.
+	var err error
+	for i, it := range d.items {
+		b, err = s.appendDictionaryMember(b, i, it.name, it.value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// appendDictionaryRange appends dict of an arbitrary Dictionary
+// implementation with its Range method.
+func (s *Serializer) appendDictionaryRange(b []byte, dict Dictionary) ([]byte, error) {
 	var err error
 	i := -1
 	dict.Range(func(name string, val Member) bool {
 		i++
-		if i > 0 {
-			b = append(b, ", "...)
-		}
-		b, err = appendKey(b, name)
-		if err != nil {
-			return false
-		}
-		b = append(b, '=')
-		b, err = appendMember(b, val)
-		if err != nil {
-			return false
-		}
-		return true
+		b, err = s.appendDictionaryMember(b, i, name, val)
+		return err == nil
 	})
 	if err != nil {
 		return nil, err
@@ -77,30 +336,66 @@ func appendDictionary(b []byte, dict Dictionary) ([]byte, error) {
 	return b, nil
 }
 
-func appendMember(b []byte, m Member) ([]byte, error) {
+// appendDictionaryMember appends the i-th member of a dictionary.
+func (s *Serializer) appendDictionaryMember(b []byte, i int, name string, val Member) ([]byte, error) {
+	if i > 0 {
+		b = append(b, s.memberSeparator()...)
+	}
+	b, err := appendKey(b, name)
+	if err != nil {
+		return nil, err
+	}
+	if it, ok := booleanTrueItem(val); ok {
+		// Boolean true is serialized as the key only.
+		return s.appendParameters(b, it.Parameters())
+	}
+	b = append(b, '=')
+	return s.appendMember(b, val)
+}
+
+func (s *Serializer) appendMember(b []byte, m Member) ([]byte, error) {
+	if m == nil {
+		return nil, errors.New("nil member")
+	}
 	var err error
 	switch m.Type() {
 	case MemberTypeInnerList:
-		b, err = appendInnerList(b, m.AsInnerList())
+		b, err = s.appendInnerList(b, m.AsInnerList())
 		if err != nil {
 			return nil, err
 		}
 	case MemberTypeItem:
-		b, err = appendItem(b, m.AsItem())
+		b, err = s.appendItem(b, m.AsItem())
 		if err != nil {
 			return nil, err
 		}
+	default:
+		return nil, errors.New("invalid member type")
 	}
 	return b, nil
 }
 
-func appendList(b []byte, list List) ([]byte, error) {
+// booleanTrueItem returns the item of m and true if m is an Item
+// whose bare item is boolean true.
+func booleanTrueItem(m Member) (Item, bool) {
+	if m == nil || m.Type() != MemberTypeItem {
+		return nil, false
+	}
+	it := m.AsItem()
+	if it == nil || it.BareItem() == nil {
+		return nil, false
+	}
+	v, ok := it.BareItem().BoolValue()
+	return it, ok && v
+}
+
+func (s *Serializer) appendList(b []byte, list List) ([]byte, error) {
 	var err error
 	for i, m := range []Member(list) {
 		if i > 0 {
-			b = append(b, ", "...)
+			b = append(b, s.memberSeparator()...)
 		}
-		b, err = appendMember(b, m)
+		b, err = s.appendMember(b, m)
 		if err != nil {
 			return nil, err
 		}
@@ -108,33 +403,39 @@ func appendList(b []byte, list List) ([]byte, error) {
 	return b, nil
 }
 
-func appendInnerList(b []byte, list InnerList) ([]byte, error) {
+func (s *Serializer) appendInnerList(b []byte, list InnerList) ([]byte, error) {
+	if list == nil {
+		return nil, errors.New("nil inner list")
+	}
 	b = append(b, '(')
 	var err error
 	for i, it := range list.Items() {
 		if i > 0 {
 			b = append(b, ' ')
 		}
-		b, err = appendItem(b, it)
+		b, err = s.appendItem(b, it)
 		if err != nil {
 			return nil, err
 		}
 	}
 	b = append(b, ')')
-	b, err = appendParameters(b, list.Parameters())
+	b, err = s.appendParameters(b, list.Parameters())
 	if err != nil {
 		return nil, err
 	}
 	return b, nil
 }
 
-func appendItem(b []byte, item Item) ([]byte, error) {
-	b, err := appendBareItem(b, item.BareItem())
+func (s *Serializer) appendItem(b []byte, item Item) ([]byte, error) {
+	if item == nil {
+		return nil, errors.New("nil item")
+	}
+	b, err := s.appendBareItem(b, item.BareItem())
 	if err != nil {
 		return nil, err
 	}
 
-	b, err = appendParameters(b, item.Parameters())
+	b, err = s.appendParameters(b, item.Parameters())
 	if err != nil {
 		return nil, err
 	}
@@ -142,25 +443,32 @@ func appendItem(b []byte, item Item) ([]byte, error) {
 	return b, err
 }
 
-func appendParameters(b []byte, params Parameters) ([]byte, error) {
+func (s *Serializer) appendParameters(b []byte, params Parameters) ([]byte, error) {
 	if params == nil || params.Len() == 0 {
 		return b, nil
 	}
+	p, ok := params.(*parameters)
+	if !ok {
+		return s.appendParametersRange(b, params)
+	}
+	// Iterate directly to avoid allocating a closure.
 	var err error
-	params.Range(func(name string, val BareItem) bool {
-		b = append(b, ';')
-		b, err = appendKey(b, name)
+	for _, it := range p.items {
+		b, err = s.appendParameter(b, it.name, it.value)
 		if err != nil {
-			return false
-		}
-		if val != nil {
-			b = append(b, '=')
-			b, err = appendBareItem(b, val)
-			if err != nil {
-				return false
-			}
+			return nil, err
 		}
-		return true
+	}
+	return b, nil
+}
+
+// appendParametersRange appends params of an arbitrary Parameters
+// implementation with its Range method.
+func (s *Serializer) appendParametersRange(b []byte, params Parameters) ([]byte, error) {
+	var err error
+	params.Range(func(name string, val BareItem) bool {
+		b, err = s.appendParameter(b, name, val)
+		return err == nil
 	})
 	if err != nil {
 		return nil, err
@@ -168,56 +476,124 @@ func appendParameters(b []byte, params Parameters) ([]byte, error) {
 	return b, nil
 }
 
-func appendBareItem(b []byte, bi BareItem) ([]byte, error) {
+func (s *Serializer) appendParameter(b []byte, name string, val BareItem) ([]byte, error) {
+	b = append(b, ';')
+	b, err := appendKey(b, name)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := val.BoolValue(); ok && v {
+		// Boolean true is serialized as the name only.
+		return b, nil
+	}
+	b = append(b, '=')
+	return s.appendBareItem(b, val)
+}
+
+func (s *Serializer) appendBareItem(b []byte, bi BareItem) ([]byte, error) {
+	if bi == nil {
+		return nil, errors.New("nil bare item")
+	}
 	switch bi.Type() {
 	case ItemTypeString:
 		return appendBareItemString(b, bi.AsString())
 	case ItemTypeByteSeq:
-		return appendBareItemByteSeq(b, bi.AsByteSeq())
+		return s.appendBareItemByteSeq(b, bi.AsByteSeq())
 	case ItemTypeBool:
 		return appendBareItemBool(b, bi.AsBool())
 	case ItemTypeInt:
-		return appendBareItemInt(b, bi.AsInt())
+		return s.appendBareItemInt(b, bi.AsInt())
 	case ItemTypeFloat:
 		return appendBareItemFloat(b, bi.AsFloat())
+	case ItemTypeDecimal:
+		return appendBareItemDecimal(b, bi.AsDecimal())
+	case ItemTypeDate:
+		return s.appendBareItemDate(b, bi.AsDate())
+	case ItemTypeDisplayString:
+		return appendBareItemDisplayString(b, bi.AsDisplayString())
 	case ItemTypeToken:
-		return appendBareItemToken(b, bi.AsToken())
+		return s.appendBareItemToken(b, bi.AsToken())
 	}
-	panic("invalid item type")
+	return nil, errors.New("invalid item type")
 }
 
-func appendBareItemInt(b []byte, v int64) ([]byte, error) {
-	if v < -999_999_999_999_999 || 999_999_999_999_999 < v {
-		return nil, errors.New("Integers may not be larger than 15 digits")
+func (s *Serializer) appendBareItemInt(b []byte, v int64) ([]byte, error) {
+	limit := integerDigitLimit(s.IntegerDigitLimit)
+	if !integerInRange(v, limit) {
+		return nil, fmt.Errorf("Integers may not be larger than %d digits", limit)
 	}
+	// AppendInt emits the canonical form, which has no "+" sign and
+	// no leading zeros. int64 has no negative zero, so "-0" parsed
+	// by Parser is serialized as "0".
 	return strconv.AppendInt(b, v, 10), nil
 }
 
+// integerInRange reports whether v is in the range of "Integer"
+// values with at most limit digits, that is, ±(10^limit - 1).
+func integerInRange(v int64, limit int) bool {
+	if limit >= 19 {
+		// Every int64 value has at most 19 digits.
+		return true
+	}
+	max := int64(1)
+	for i := 0; i < limit; i++ {
+		max *= 10
+	}
+	max--
+	return -max <= v && v <= max
+}
+
+// appendBareItemFloat appends v rounded to three fractional digits
+// with round-half-to-even, following the decimal representation of v.
 func appendBareItemFloat(b []byte, v float64) ([]byte, error) {
-	formatted := strconv.FormatFloat(v, 'f', -1, 64)
-	parts := strings.Split(formatted, ".")
-	if len(parts[0]) > 15 || (v > 0 && len(parts[0]) > 14) {
-		return nil, errors.New("When serializing floats, the integer part may not be larger than 14 digits")
-	}
-	b = append(b, parts[0]...)
-	b = append(b, '.')
-	if len(parts) <= 1 {
-		b = append(b, '0')
-	} else {
-		fracLen := len(parts[1])
-		if fracLen > 15-len(parts[0]) {
-			fracLen = 15 - len(parts[0])
-		}
-		b = append(b, parts[1][:fracLen]...)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, errors.New("NaN and infinity cannot be serialized as floats")
 	}
-	return b, nil
+	formatted := strconv.FormatFloat(math.Abs(v), 'f', -1, 64)
+	parts := strings.SplitN(formatted, ".", 2)
+	intPart := parts[0]
+	var fracPart string
+	if len(parts) > 1 {
+		fracPart = parts[1]
+	}
+	if len(intPart) > decimalIntDigits {
+		return nil, fmt.Errorf("When serializing floats, the integer part may not be larger than %d digits", decimalIntDigits)
+	}
+
+	milli, err := roundToMilli(intPart, fracPart)
+	if err != nil {
+		return nil, err
+	}
+	if milli > maxDecimalMilliAbs {
+		return nil, fmt.Errorf("When serializing floats, the integer part may not be larger than %d digits", decimalIntDigits)
+	}
+	// Negative zero and negative values rounded to zero have
+	// milli == 0, so they are serialized as "0.0", not "-0.0".
+	if v < 0 {
+		milli = -milli
+	}
+	return appendMilli(b, milli), nil
+}
+
+func appendBareItemDecimal(b []byte, v Decimal) ([]byte, error) {
+	if v.milli < -maxDecimalMilliAbs || maxDecimalMilliAbs < v.milli {
+		return nil, fmt.Errorf("Decimals may not have more than %d integer digits", decimalIntDigits)
+	}
+	return appendMilli(b, v.milli), nil
+}
+
+func (s *Serializer) appendBareItemDate(b []byte, v time.Time) ([]byte, error) {
+	if v.Nanosecond() != 0 {
+		return nil, errors.New("Dates must be integer seconds")
+	}
+	return s.appendBareItemInt(append(b, '@'), v.Unix())
 }
 
 func appendBareItemString(b []byte, val string) ([]byte, error) {
 	b = append(b, '"')
-	for _, c := range []byte(val) {
-		if c < ' ' || c > '~' {
-			return nil, errors.New("invalid character in string")
+	for i, c := range []byte(val) {
+		if !isStringChar(c) {
+			return nil, fmt.Errorf("invalid character 0x%02x in string at offset %d", c, i)
 		}
 		if c == '\\' || c == '"' {
 			b = append(b, '\\')
@@ -228,18 +604,48 @@ func appendBareItemString(b []byte, val string) ([]byte, error) {
 	return b, nil
 }
 
-func appendBareItemToken(b []byte, token Token) ([]byte, error) {
-	m := tokenRegex.FindStringIndex(string(token))
-	if len(m) == 0 || m[1] != len(string(token)) {
+func appendBareItemDisplayString(b []byte, val DisplayString) ([]byte, error) {
+	if !utf8.ValidString(string(val)) {
+		return nil, errors.New("display strings must be valid UTF-8")
+	}
+	const hexDigits = "0123456789abcdef"
+	b = append(b, '%', '"')
+	for _, c := range []byte(val) {
+		if c == '%' || c == '"' || c < ' ' || c > '~' {
+			b = append(b, '%', hexDigits[c>>4], hexDigits[c&0xf])
+			continue
+		}
+		b = append(b, c)
+	}
+	b = append(b, '"')
+	return b, nil
+}
+
+func (s *Serializer) appendBareItemToken(b []byte, token Token) ([]byte, error) {
+	if !ValidToken(string(token)) {
 		return nil, errors.New("invalid token value")
 	}
+	// A leading asterisk starts a "Byte Sequence" in draft-14.
+	if !s.RFC8941 && token[0] == '*' {
+		return nil, errors.New("tokens must not start with * unless RFC8941 is set")
+	}
 	return append(b, token...), nil
 }
 
-func appendBareItemByteSeq(b []byte, data []byte) ([]byte, error) {
-	b = append(b, '*')
-	b = append(b, base64.StdEncoding.EncodeToString(data)...)
-	b = append(b, '*')
+func (s *Serializer) appendBareItemByteSeq(b []byte, data []byte) ([]byte, error) {
+	delim := byte('*')
+	if s.RFC8941 {
+		delim = ':'
+	}
+	b = append(b, delim)
+	start := len(b)
+	var enc byteSeqCodec = base64.StdEncoding
+	if s.ByteSeqEncoding == ByteSeqBase32 {
+		enc = base32.StdEncoding
+	}
+	b = append(b, make([]byte, enc.EncodedLen(len(data)))...)
+	enc.Encode(b[start:], data)
+	b = append(b, delim)
 	return b, nil
 }
 
@@ -254,8 +660,7 @@ func appendBareItemBool(b []byte, v bool) ([]byte, error) {
 }
 
 func appendKey(b []byte, key string) ([]byte, error) {
-	m := keyRegex.FindStringIndex(key)
-	if len(m) == 0 || m[1] != len(key) {
+	if !ValidKey(key) {
 		return nil, errors.New("keys must start with a-z and only contain a-z0-9_-")
 	}
 	return append(b, key...), nil
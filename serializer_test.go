@@ -0,0 +1,533 @@
+package stheader_test
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestSerialize(t *testing.T) {
+	item := stheader.NewItem(stheader.NewBareItem(int64(1)), nil)
+	got, err := stheader.Serialize(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1"; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+
+	list := stheader.List{stheader.NewMember(item), stheader.NewMember(item)}
+	got, err = stheader.Serialize(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1, 1"; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+
+	dict := stheader.NewDictionary()
+	dict.Store("a", stheader.NewMember(item))
+	got, err = stheader.Serialize(dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a=1"; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+
+	if _, err := stheader.Serialize(1); err == nil {
+		t.Error("should return an error for an invalid value type")
+	}
+}
+
+func TestSerializeIntegerDigitLimit(t *testing.T) {
+	testCases := []struct {
+		value   int64
+		limit   int
+		wantErr bool
+	}{
+		{value: 999_999_999_999_999},
+		{value: -999_999_999_999_999},
+		{value: 1_000_000_000_000_000, wantErr: true},
+		{value: -1_000_000_000_000_000, wantErr: true},
+		{value: 999_999_999_999, limit: 12},
+		{value: -999_999_999_999, limit: 12},
+		{value: 1_000_000_000_000, limit: 12, wantErr: true},
+		{value: -1_000_000_000_000, limit: 12, wantErr: true},
+	}
+	for _, c := range testCases {
+		s := stheader.Serializer{IntegerDigitLimit: c.limit}
+		_, err := s.SerializeItem(stheader.NewItem(stheader.NewBareItem(c.value), nil))
+		if c.wantErr && err == nil {
+			t.Errorf("should fail for %d (limit=%d)", c.value, c.limit)
+		} else if !c.wantErr && err != nil {
+			t.Errorf("unexpected error for %d (limit=%d): %s", c.value, c.limit, err)
+		}
+	}
+}
+
+func TestSerializeIntegerCanonical(t *testing.T) {
+	testCases := []struct {
+		input string
+		limit int
+		want  string
+	}{
+		{input: "0", want: "0"},
+		{input: "-0", want: "0"},
+		{input: "-000", want: "0"},
+		{input: "007", want: "7"},
+		{input: "-007", want: "-7"},
+		{input: "999999999999999", want: "999999999999999"},
+		{input: "-999999999999999", want: "-999999999999999"},
+		{input: "9223372036854775807", limit: 19, want: "9223372036854775807"},
+		{input: "-9223372036854775808", limit: 19, want: "-9223372036854775808"},
+	}
+	for _, c := range testCases {
+		p := stheader.NewParser(c.input)
+		p.IntegerDigitLimit = c.limit
+		item, err := p.ParseItem()
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", c.input, err)
+		}
+		s := stheader.Serializer{IntegerDigitLimit: c.limit}
+		got, err := s.SerializeItem(item)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", c.input, err)
+		}
+		if got != c.want {
+			t.Errorf("result mismatch for %q, got=%q, want=%q", c.input, got, c.want)
+		}
+	}
+	if _, err := stheader.NewParser("+1").ParseItem(); err == nil {
+		t.Error("should fail for a leading plus sign")
+	}
+}
+
+func TestSerializeFloatRounding(t *testing.T) {
+	testCases := []struct {
+		value   float64
+		want    string
+		wantErr bool
+	}{
+		{value: 0.0005, want: "0.0"},
+		{value: 0.0015, want: "0.002"},
+		{value: 1.2345, want: "1.234"},
+		{value: 1.2355, want: "1.236"},
+		{value: 1.23451, want: "1.235"},
+		{value: -0.0005, want: "0.0"},
+		{value: math.Copysign(0, -1), want: "0.0"},
+		{value: -0.0001, want: "0.0"},
+		{value: -1e-300, want: "0.0"},
+		{value: -0.0015, want: "-0.002"},
+		{value: -1.2345, want: "-1.234"},
+		{value: 2, want: "2.0"},
+		{value: 0.12, want: "0.12"},
+		{value: 999_999_999_999.999, want: "999999999999.999"},
+		{value: 999_999_999_999.9995, wantErr: true},
+		{value: 1_000_000_000_000, wantErr: true},
+	}
+	for _, c := range testCases {
+		got, err := stheader.Serialize(stheader.NewItem(stheader.NewBareItem(c.value), nil))
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("should fail for %v, got=%q", c.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %v: %s", c.value, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("result mismatch for %v, got=%q, want=%q", c.value, got, c.want)
+		}
+	}
+
+	for _, input := range []string{"-0.0", "-0.000", "-0.0004"} {
+		p := stheader.NewParser(input)
+		p.RoundFractionalDigits = true
+		item, err := p.ParseItem()
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", input, err)
+		}
+		got, err := stheader.Serialize(item)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", input, err)
+		}
+		if want := "0.0"; got != want {
+			t.Errorf("result mismatch for %q, got=%q, want=%q", input, got, want)
+		}
+	}
+}
+
+func TestSerializeDate(t *testing.T) {
+	item := stheader.NewItem(stheader.NewBareItem(time.Unix(1, 500)), nil)
+	if _, err := stheader.Serialize(item); err == nil {
+		t.Error("should fail for a date with fractional seconds")
+	}
+	item = stheader.NewItem(stheader.NewBareItem(time.Unix(1_000_000_000_000_000, 0)), nil)
+	if _, err := stheader.Serialize(item); err == nil {
+		t.Error("should fail for a date out of the integer range")
+	}
+}
+
+func TestSerializeDisplayString(t *testing.T) {
+	item := stheader.NewItem(stheader.NewBareItem(stheader.DisplayString("\xff")), nil)
+	if _, err := stheader.Serialize(item); err == nil {
+		t.Error("should fail for invalid UTF-8")
+	}
+}
+
+func TestSerializeEmpty(t *testing.T) {
+	var s stheader.Serializer
+	for _, list := range []stheader.List{nil, {}} {
+		got, err := s.SerializeList(list)
+		if err != nil {
+			t.Errorf("unexpected error for list %#v: %s", list, err)
+		} else if got != "" {
+			t.Errorf("result mismatch for list %#v, got=%q, want=%q", list, got, "")
+		}
+	}
+	for _, dict := range []stheader.Dictionary{nil, stheader.NewDictionary()} {
+		got, err := s.SerializeDictionary(dict)
+		if err != nil {
+			t.Errorf("unexpected error for dictionary %#v: %s", dict, err)
+		} else if got != "" {
+			t.Errorf("result mismatch for dictionary %#v, got=%q, want=%q", dict, got, "")
+		}
+	}
+}
+
+func TestNewSerializer(t *testing.T) {
+	list, err := stheader.NewParser(`a, b;x=*aGk=*, (c d)`).ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict, err := stheader.NewParser(`a=1, b=2`).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		opts     []stheader.SerializerOption
+		wantList string
+		wantDict string
+	}{
+		{
+			wantList: `a, b;x=*aGk=*, (c d)`,
+			wantDict: `a=1, b=2`,
+		},
+		{
+			opts:     []stheader.SerializerOption{stheader.WithCompact()},
+			wantList: `a,b;x=*aGk=*,(c d)`,
+			wantDict: `a=1,b=2`,
+		},
+		{
+			opts:     []stheader.SerializerOption{stheader.WithCompact(), stheader.WithRFC8941()},
+			wantList: `a,b;x=:aGk=:,(c d)`,
+			wantDict: `a=1,b=2`,
+		},
+	}
+	for _, c := range testCases {
+		s := stheader.NewSerializer(c.opts...)
+		got, err := s.Serialize(list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.wantList {
+			t.Errorf("list mismatch, got=%q, want=%q", got, c.wantList)
+		}
+		got, err = s.Serialize(dict)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.wantDict {
+			t.Errorf("dictionary mismatch, got=%q, want=%q", got, c.wantDict)
+		}
+	}
+
+	s := stheader.NewSerializer(stheader.WithIntegerDigitLimit(2))
+	if _, err := s.Serialize(stheader.NewItem(stheader.NewBareItem(int64(100)), nil)); err == nil {
+		t.Error("should fail for an integer exceeding the digit limit")
+	}
+}
+
+func TestSerializeMemberAndBareItem(t *testing.T) {
+	list, err := stheader.NewParser(`a;x=1, (b "c");y`).ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		value interface{}
+		want  string
+	}{
+		{value: list[0], want: `a;x=1`},
+		{value: list[1], want: `(b "c");y`},
+		{value: list[0].AsItem().BareItem(), want: `a`},
+		{value: stheader.NewBareItem(stheader.NewDecimal(1500)), want: `1.5`},
+	}
+	for _, c := range testCases {
+		got, err := stheader.Serialize(c.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("result mismatch, got=%q, want=%q", got, c.want)
+		}
+	}
+
+	var s stheader.Serializer
+	if got, err := s.SerializeMember(list[1]); err != nil || got != `(b "c");y` {
+		t.Errorf("SerializeMember result mismatch, got=%q, err=%v", got, err)
+	}
+	if got, err := s.SerializeBareItem(stheader.NewBareItem("foo")); err != nil || got != `"foo"` {
+		t.Errorf("SerializeBareItem result mismatch, got=%q, err=%v", got, err)
+	}
+	if _, err := s.SerializeMember(nil); err == nil {
+		t.Error("should fail for a nil member")
+	}
+	if _, err := s.SerializeBareItem(nil); err == nil {
+		t.Error("should fail for a nil bare item")
+	}
+}
+
+func TestSerializeBareItem(t *testing.T) {
+	testCases := []struct {
+		value interface{}
+		want  string
+	}{
+		{value: "foo", want: `"foo"`},
+		{value: []byte("hi"), want: `*aGk=*`},
+		{value: []byte{}, want: `**`},
+		{value: true, want: `?1`},
+		{value: int64(-42), want: `-42`},
+		{value: 2.5, want: `2.5`},
+		{value: stheader.Token("text/html"), want: `text/html`},
+		{value: stheader.NewDecimal(-1001), want: `-1.001`},
+		{value: time.Unix(1659578233, 0), want: `@1659578233`},
+		{value: stheader.DisplayString("füü"), want: `%"f%c3%bc%c3%bc"`},
+	}
+	for _, c := range testCases {
+		got, err := stheader.SerializeBareItem(stheader.NewBareItem(c.value))
+		if err != nil {
+			t.Errorf("unexpected error for %#v: %s", c.value, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("result mismatch for %#v, got=%q, want=%q", c.value, got, c.want)
+		}
+	}
+
+	for _, value := range []interface{}{
+		stheader.Token("1a"),
+		int64(1_000_000_000_000_000),
+		"\x00",
+	} {
+		if _, err := stheader.SerializeBareItem(stheader.NewBareItem(value)); err == nil {
+			t.Errorf("should fail for %#v", value)
+		}
+	}
+}
+
+func TestSerializeInvalidString(t *testing.T) {
+	testCases := []struct {
+		input   string
+		wantErr string
+	}{
+		{input: "a\tb", wantErr: "invalid character 0x09 in string at offset 1"},
+		{input: "ab\n", wantErr: "invalid character 0x0a in string at offset 2"},
+		{input: "\x7f", wantErr: "invalid character 0x7f in string at offset 0"},
+		{input: "fü", wantErr: "invalid character 0xc3 in string at offset 1"},
+	}
+	for _, c := range testCases {
+		if stheader.ValidString(c.input) {
+			t.Errorf("ValidString should return false for %q", c.input)
+		}
+		_, err := stheader.SerializeBareItem(stheader.NewBareItem(c.input))
+		if err == nil {
+			t.Errorf("should fail for %q", c.input)
+			continue
+		}
+		if got := err.Error(); got != c.wantErr {
+			t.Errorf("error mismatch for %q, got=%q, want=%q", c.input, got, c.wantErr)
+		}
+	}
+
+	for _, input := range []string{"", "foo bar", ` !"\~`} {
+		if !stheader.ValidString(input) {
+			t.Errorf("ValidString should return true for %q", input)
+		}
+	}
+}
+
+func TestSerializerAppend(t *testing.T) {
+	list, err := stheader.NewParser(`a;x=1, (b c)`).ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict, err := stheader.NewParser(`k=1, l`).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s stheader.Serializer
+	b := []byte("prefix:")
+	b, err = s.AppendList(b, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = append(b, '|')
+	b, err = s.AppendDictionary(b, dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = append(b, '|')
+	b, err = s.AppendItem(b, list[0].AsItem())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `prefix:a;x=1, (b c)|k=1, l|a;x=1`; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+
+	invalid := stheader.NewItem(stheader.NewBareItem(stheader.Token("1a")), nil)
+	got, err := s.AppendItem(b, invalid)
+	if err == nil {
+		t.Error("should fail for an invalid token")
+	}
+	if string(got) != string(b) {
+		t.Errorf("buffer should be unchanged on error, got=%q, want=%q", got, b)
+	}
+}
+
+func TestSerializerAppendNoAlloc(t *testing.T) {
+	dict, err := stheader.NewParser(`a=text/html;q=0.5;charset="utf-8", b, c=(1 2);x=*aGk=*`).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s stheader.Serializer
+	buf := make([]byte, 0, 128)
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := s.AppendDictionary(buf[:0], dict); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("AppendDictionary should not allocate, got=%v", allocs)
+	}
+}
+
+func BenchmarkSerializerAppendItem(b *testing.B) {
+	item, err := stheader.NewParser(`text/html;q=0.5;charset="utf-8"`).ParseItem()
+	if err != nil {
+		b.Fatal(err)
+	}
+	var s stheader.Serializer
+	buf := make([]byte, 0, 64)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, err = s.AppendItem(buf[:0], item)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSerializeRealisticDictionary(b *testing.B) {
+	dict, err := stheader.NewParser(benchmarkRealisticDictionary).ParseDictionary()
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkAppend(b, dict)
+}
+
+func BenchmarkSerializeTokenList(b *testing.B) {
+	list, err := stheader.NewParser(benchmarkTokenList).ParseList()
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkAppend(b, list)
+}
+
+func BenchmarkSerializeByteSeqDictionary(b *testing.B) {
+	dict, err := stheader.NewParser(benchmarkByteSeqDictionary()).ParseDictionary()
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkAppend(b, dict)
+}
+
+// benchmarkAppend benchmarks appending value, which must be
+// a Dictionary or a List, to a reused buffer.
+func benchmarkAppend(b *testing.B, value interface{}) {
+	var s stheader.Serializer
+	var buf []byte
+	var err error
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		switch v := value.(type) {
+		case stheader.Dictionary:
+			buf, err = s.AppendDictionary(buf[:0], v)
+		case stheader.List:
+			buf, err = s.AppendList(buf[:0], v)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestSerializerWrite(t *testing.T) {
+	list, err := stheader.NewParser(`a, (b c);x`).ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict, err := stheader.NewParser(`k=1, l`).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s stheader.Serializer
+	var buf bytes.Buffer
+	n, err := s.WriteList(&buf, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `a, (b c);x`; buf.String() != want || n != len(want) {
+		t.Errorf("WriteList result mismatch, got=%q (n=%d), want=%q", buf.String(), n, want)
+	}
+	buf.Reset()
+	if _, err := s.WriteDictionary(&buf, dict); err != nil {
+		t.Fatal(err)
+	}
+	if want := `k=1, l`; buf.String() != want {
+		t.Errorf("WriteDictionary result mismatch, got=%q, want=%q", buf.String(), want)
+	}
+	buf.Reset()
+	if _, err := s.WriteItem(&buf, list[0].AsItem()); err != nil {
+		t.Fatal(err)
+	}
+	if want := `a`; buf.String() != want {
+		t.Errorf("WriteItem result mismatch, got=%q, want=%q", buf.String(), want)
+	}
+
+	if _, err := s.WriteList(failingWriter{}, list); err == nil || err.Error() != "write failed" {
+		t.Errorf("should return the writer error, got=%v", err)
+	}
+
+	buf.Reset()
+	invalid := stheader.List{list[0], stheader.NewMember(stheader.NewItem(stheader.NewBareItem(stheader.Token("1a")), nil))}
+	if _, err := s.WriteList(&buf, invalid); err == nil {
+		t.Error("should fail for an invalid token")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("nothing should be written for an invalid value, got=%q", buf.String())
+	}
+}
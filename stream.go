@@ -0,0 +1,75 @@
+package stheader
+
+import "errors"
+
+// ErrIncomplete is wrapped by the error which StreamParser returns
+// when the input ends in the middle of a value.
+var ErrIncomplete = errors.New("stheader: incomplete input")
+
+// StreamParser parses a header value which is fed in chunks.
+// It accumulates the chunks and parses them when one of Finish*
+// methods is called.
+//
+// The zero value is ready to use.
+type StreamParser struct {
+	buf []byte
+}
+
+// NewStreamParser creates a new StreamParser.
+func NewStreamParser() *StreamParser {
+	return &StreamParser{}
+}
+
+// Feed appends a chunk of the input.
+func (s *StreamParser) Feed(chunk []byte) {
+	s.buf = append(s.buf, chunk...)
+}
+
+// Write appends a chunk of the input. It implements io.Writer and
+// never returns an error.
+func (s *StreamParser) Write(chunk []byte) (int, error) {
+	s.Feed(chunk)
+	return len(chunk), nil
+}
+
+// FinishDictionary parses the fed input as Dictionary.
+func (s *StreamParser) FinishDictionary() (Dictionary, error) {
+	dict, err := NewParserBytes(s.buf).ParseDictionary()
+	if err != nil {
+		return nil, incompleteError(err)
+	}
+	return dict, nil
+}
+
+// FinishList parses the fed input as List.
+func (s *StreamParser) FinishList() (List, error) {
+	list, err := NewParserBytes(s.buf).ParseList()
+	if err != nil {
+		return nil, incompleteError(err)
+	}
+	return list, nil
+}
+
+// FinishItem parses the fed input as Item.
+func (s *StreamParser) FinishItem() (Item, error) {
+	item, err := NewParserBytes(s.buf).ParseItem()
+	if err != nil {
+		return nil, incompleteError(err)
+	}
+	return item, nil
+}
+
+// incompleteError converts an error for the end of input to
+// a ParseError wrapping ErrIncomplete.
+func incompleteError(err error) error {
+	perr, ok := err.(*ParseError)
+	if !ok || perr.kind != ErrUnexpectedEOF {
+		return err
+	}
+	return &ParseError{
+		msg:  "Incomplete input: " + perr.msg,
+		pos:  perr.pos,
+		kind: ErrIncomplete,
+		err:  perr.err,
+	}
+}
@@ -0,0 +1,62 @@
+package stheader_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestStreamParser(t *testing.T) {
+	const input = `a=(1 2);x, b="foo", c=*aGVsbG8=*;y=?0`
+	s := stheader.NewStreamParser()
+	for i := 0; i < len(input); i++ {
+		s.Feed([]byte{input[i]})
+	}
+	dict, err := s.FinishDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(dict); got != input {
+		t.Errorf("result mismatch, got=%q, want=%q", got, input)
+	}
+}
+
+func TestStreamParserIncomplete(t *testing.T) {
+	testCases := []struct {
+		input      string
+		headerType string
+	}{
+		{input: `"foo`, headerType: "item"},
+		{input: `(1 2`, headerType: "list"},
+		{input: `a, b,`, headerType: "list"},
+		{input: `a=1,`, headerType: "dictionary"},
+	}
+	for _, c := range testCases {
+		var s stheader.StreamParser
+		for i := 0; i < len(c.input); i++ {
+			if _, err := s.Write([]byte{c.input[i]}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var err error
+		switch c.headerType {
+		case "item":
+			_, err = s.FinishItem()
+		case "list":
+			_, err = s.FinishList()
+		case "dictionary":
+			_, err = s.FinishDictionary()
+		}
+		if !errors.Is(err, stheader.ErrIncomplete) {
+			t.Errorf("error mismatch for %q, got=%v, want=%v", c.input, err, stheader.ErrIncomplete)
+		}
+	}
+
+	var s stheader.StreamParser
+	s.Feed([]byte("a=?2"))
+	if _, err := s.FinishDictionary(); err == nil || errors.Is(err, stheader.ErrIncomplete) {
+		t.Errorf("invalid input should not be reported as incomplete, got=%v", err)
+	}
+}
@@ -1,8 +1,25 @@
 package stheader
 
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
 // Token is the type of tokens, which is short textual words.
+//
+// A token starting with "*" is valid only in RFC 8941 since "*" starts
+// a "Byte Sequence" in draft-ietf-httpbis-header-structure-14. Both
+// Serializer.RFC8941 and Parser.RFC8941 must be set to round-trip it.
 type Token string
 
+// DisplayString is the type of display strings, which are Unicode
+// strings to be displayed to users.
+type DisplayString string
+
 // ItemType is the enumerated type of BareItem.
 type ItemType int
 
@@ -14,15 +31,23 @@ const (
 	ItemTypeInt
 	ItemTypeFloat
 	ItemTypeToken
+	ItemTypeDecimal
+	ItemTypeDate
+	ItemTypeDisplayString
 )
 
 // BareItem is Item without Parameters.
 // BareItem is one of "String", "Byte Sequence", "Boolean", "Integer",
-// "Float", or "Token" value.
+// "Float", "Token", "Decimal", "Date", or "Display String" value.
 type BareItem interface {
 	// Type returns the item type.
 	Type() ItemType
 
+	// IsInteger reports whether the value is an "Integer".
+	// It returns false for "Decimal" and "Float" values, so callers
+	// can tell "5" from "5.0" in the parsed input.
+	IsInteger() bool
+
 	// AsString returns the "String" value.
 	// It panics if item type is not ItemTypeString.
 	AsString() string
@@ -46,6 +71,54 @@ type BareItem interface {
 	// AsToken returns the "Token" value.
 	// It panics if item type is not ItemTypeToken.
 	AsToken() Token
+
+	// AsDecimal returns the "Decimal" value.
+	// It panics if item type is not ItemTypeDecimal.
+	AsDecimal() Decimal
+
+	// AsDate returns the "Date" value.
+	// It panics if item type is not ItemTypeDate.
+	AsDate() time.Time
+
+	// AsDisplayString returns the "Display String" value.
+	// It panics if item type is not ItemTypeDisplayString.
+	AsDisplayString() DisplayString
+
+	// StringValue returns the "String" value and true,
+	// or "" and false if item type is not ItemTypeString.
+	StringValue() (string, bool)
+
+	// ByteSeqValue returns the "Byte Sequence" value and true,
+	// or nil and false if item type is not ItemTypeByteSeq.
+	ByteSeqValue() ([]byte, bool)
+
+	// BoolValue returns the "Boolean" value and true,
+	// or false and false if item type is not ItemTypeBool.
+	BoolValue() (bool, bool)
+
+	// IntValue returns the "Integer" value and true,
+	// or 0 and false if item type is not ItemTypeInt.
+	IntValue() (int64, bool)
+
+	// FloatValue returns the "Float" value and true,
+	// or 0 and false if item type is not ItemTypeFloat.
+	FloatValue() (float64, bool)
+
+	// TokenValue returns the "Token" value and true,
+	// or "" and false if item type is not ItemTypeToken.
+	TokenValue() (Token, bool)
+
+	// DecimalValue returns the "Decimal" value and true,
+	// or zero and false if item type is not ItemTypeDecimal.
+	DecimalValue() (Decimal, bool)
+
+	// DateValue returns the "Date" value and true,
+	// or zero time and false if item type is not ItemTypeDate.
+	DateValue() (time.Time, bool)
+
+	// DisplayStringValue returns the "Display String" value and true,
+	// or "" and false if item type is not ItemTypeDisplayString.
+	DisplayStringValue() (DisplayString, bool)
 }
 
 // Item is BareItem with optional Parameters.
@@ -56,6 +129,15 @@ type Item interface {
 	// Parameters returns the optional parameters in Item.
 	// It returns nil if Item has no parameters.
 	Parameters() Parameters
+
+	// Param returns the value of the parameter and true if found,
+	// nil and false otherwise, including when Item has no parameters.
+	Param(name string) (BareItem, bool)
+
+	// RawBytes returns the source text of the Item exactly as
+	// received. It is only populated for the top-level Item parsed
+	// with Parser.RecordRaw set, and returns nil otherwise.
+	RawBytes() []byte
 }
 
 // Parameters is an ordered map of string key to BareItem.
@@ -67,6 +149,9 @@ type Parameters interface {
 	// nil and false otherwise.
 	Load(name string) (value BareItem, ok bool)
 
+	// LoadOrDefault returns the value if found, def otherwise.
+	LoadOrDefault(name string, def BareItem) BareItem
+
 	// Range calls f sequentially for each key and value present
 	// in the parameters. If f returns false, range stops the iteration.
 	//
@@ -77,12 +162,46 @@ type Parameters interface {
 	// the Range call.
 	Range(f func(name string, value BareItem) bool)
 
+	// RangeSorted is like Range but visits the parameters in
+	// lexicographic order of names. It does not change the stored
+	// order, so serialization still uses the insertion order.
+	RangeSorted(f func(name string, value BareItem) bool)
+
 	// Store sets the value for a name.
+	// A nil value is stored as Boolean true, which is the value of
+	// a parameter without one.
 	Store(name string, value BareItem)
 
+	// StoreAll calls Store for each entry in order. A name which
+	// already exists, including one earlier in entries, keeps its
+	// position and has its value overwritten by the later entry.
+	StoreAll(entries []Parameter)
+
 	// Len returns the count of mapping.
 	// It returns 0 if the parameters is empty.
 	Len() int
+
+	// Keys returns the names of the parameters in insertion order.
+	Keys() []string
+
+	// Values returns the values of the parameters in insertion order.
+	Values() []BareItem
+
+	// Clone returns a deep copy of the parameters.
+	// "Byte Sequence" values are copied, not shared.
+	Clone() Parameters
+
+	// Merge returns new parameters which have the parameters of this
+	// followed by the parameters of other whose names are not in this.
+	// For a name in both, the value of other is used at the position
+	// of this. The values are deep copied and neither is modified.
+	Merge(other Parameters) Parameters
+}
+
+// Parameter is a pair of a name and a value for Parameters.StoreAll.
+type Parameter struct {
+	Name  string
+	Value BareItem
 }
 
 // MemberType is the enumerated type of Member.
@@ -109,6 +228,12 @@ type Member interface {
 	// AsInnerList returns the "InnerList" value.
 	// It panics if item type is not MemberTypeInnerList.
 	AsInnerList() InnerList
+
+	// RawBytes returns the source text of the member exactly as
+	// received, which does not include the key of Dictionary.
+	// It is only populated for members parsed with Parser.RecordRaw
+	// set, and returns nil otherwise.
+	RawBytes() []byte
 }
 
 // InnerList is the nested list in List.
@@ -119,11 +244,36 @@ type InnerList interface {
 	// Parameters returns the optional parameters in Item.
 	// It returns nil if Item has no parameters.
 	Parameters() Parameters
+
+	// Param returns the value of the parameter and true if found,
+	// nil and false otherwise, including when InnerList has no
+	// parameters.
+	Param(name string) (BareItem, bool)
+
+	// WithItem returns a new InnerList which has the items of this
+	// InnerList followed by item, and the same parameters.
+	// This InnerList is not modified.
+	WithItem(item Item) InnerList
 }
 
 // List is an ordered list of Member.
 type List []Member
 
+// AppendList returns a new List which has the members of a followed
+// by the members of b. Neither a nor b is modified.
+func AppendList(a, b List) List {
+	l := make(List, 0, len(a)+len(b))
+	l = append(l, a...)
+	return append(l, b...)
+}
+
+// DictionaryEntry is a pair of a name and a value for
+// Dictionary.StoreAll.
+type DictionaryEntry struct {
+	Name  string
+	Value Member
+}
+
 // Parameters is an ordered map of string key to Member.
 type Dictionary interface {
 	// Delete deletes a parameter of the specified name.
@@ -133,6 +283,16 @@ type Dictionary interface {
 	// nil and false otherwise.
 	Load(name string) (value Member, ok bool)
 
+	// LoadOrDefault returns the value if found and not nil,
+	// def otherwise.
+	LoadOrDefault(name string, def Member) Member
+
+	// LoadFold is like Load but matches name case-insensitively and
+	// returns the first matching member. Keys are lowercase in the
+	// specification, so this is only a leniency helper for
+	// applications which look up keys with mixed case names.
+	LoadFold(name string) (value Member, ok bool)
+
 	//  Range calls f sequentially for each key and value present
 	// in the parameters. If f returns false, range stops the iteration.
 	//
@@ -146,9 +306,37 @@ type Dictionary interface {
 	// Store sets the value for a name.
 	Store(name string, value Member)
 
+	// Rename changes the key oldName to newName keeping the position
+	// of the member. It returns an error if oldName is not found or
+	// newName already exists.
+	Rename(oldName, newName string) error
+
+	// StoreAll calls Store for each entry in order. A name which
+	// already exists, including one earlier in entries, keeps its
+	// position and has its value overwritten by the later entry.
+	StoreAll(entries []DictionaryEntry)
+
+	// InsertAt inserts a member at index, shifting the member at index
+	// and the later ones. index must be in the range [0, Len()].
+	// It returns an error if index is out of range or name already
+	// exists.
+	InsertAt(index int, name string, value Member) error
+
+	// MoveTo moves the member of name to index, shifting the members
+	// in between. index must be in the range [0, Len()-1].
+	// It returns an error if name is not found or index is out of
+	// range.
+	MoveTo(name string, index int) error
+
 	// Len returns the count of mapping.
 	// It returns 0 if the parameters is empty.
 	Len() int
+
+	// Keys returns the keys of the dictionary in insertion order.
+	Keys() []string
+
+	// Members returns the members of the dictionary in insertion order.
+	Members() []Member
 }
 
 type bareItem struct {
@@ -156,17 +344,63 @@ type bareItem struct {
 }
 
 // NewBareItem creates a new BareItem.
-// It panics if value type is not one of the return value type
-// of BareItem As* methods.
+// val must be one of the return value types of BareItem As* methods,
+// or int, int32, uint or float32, which are converted to int64 or
+// float64.
+// It panics if value type is not supported or a uint value
+// overflows int64.
 func NewBareItem(val interface{}) BareItem {
-	bi := &bareItem{val: val}
-	// Do type check
-	bi.Type()
+	bi, err := NewBareItemChecked(val)
+	if err != nil {
+		panic(err.Error())
+	}
 	return bi
 }
 
+// NewBareItemChecked is like NewBareItem but returns an error instead
+// of panicking if value type is not supported or a uint value
+// overflows int64.
+func NewBareItemChecked(val interface{}) (BareItem, error) {
+	v, err := normalizeBareItemValue(val)
+	if err != nil {
+		return nil, err
+	}
+	return &bareItem{val: v}, nil
+}
+
+// normalizeBareItemValue converts val to the value type of BareItem.
+func normalizeBareItemValue(val interface{}) (interface{}, error) {
+	switch v := val.(type) {
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case uint:
+		if uint64(v) > math.MaxInt64 {
+			return nil, fmt.Errorf("uint value overflows int64: %d", v)
+		}
+		return int64(v), nil
+	case float32:
+		return float64(v), nil
+	}
+	if bareItemType(val) == ItemTypeInvalid {
+		return nil, fmt.Errorf("invalid bare item type: %T", val)
+	}
+	return val, nil
+}
+
 func (i *bareItem) Type() ItemType {
-	switch i.val.(type) {
+	t := bareItemType(i.val)
+	if t == ItemTypeInvalid {
+		panic("invalid BareItem type")
+	}
+	return t
+}
+
+// bareItemType returns the item type for val, or ItemTypeInvalid
+// if val is not a valid value for BareItem.
+func bareItemType(val interface{}) ItemType {
+	switch val.(type) {
 	case string:
 		return ItemTypeString
 	case []byte:
@@ -179,11 +413,22 @@ func (i *bareItem) Type() ItemType {
 		return ItemTypeFloat
 	case Token:
 		return ItemTypeToken
+	case Decimal:
+		return ItemTypeDecimal
+	case time.Time:
+		return ItemTypeDate
+	case DisplayString:
+		return ItemTypeDisplayString
 	default:
-		panic("invalid BareItem type")
+		return ItemTypeInvalid
 	}
 }
 
+func (i *bareItem) IsInteger() bool {
+	_, ok := i.val.(int64)
+	return ok
+}
+
 func (i *bareItem) AsString() string {
 	return i.val.(string)
 }
@@ -208,9 +453,100 @@ func (i *bareItem) AsToken() Token {
 	return i.val.(Token)
 }
 
+func (i *bareItem) AsDecimal() Decimal {
+	return i.val.(Decimal)
+}
+
+func (i *bareItem) AsDate() time.Time {
+	return i.val.(time.Time)
+}
+
+func (i *bareItem) AsDisplayString() DisplayString {
+	return i.val.(DisplayString)
+}
+
+func (i *bareItem) StringValue() (string, bool) {
+	v, ok := i.val.(string)
+	return v, ok
+}
+
+func (i *bareItem) ByteSeqValue() ([]byte, bool) {
+	v, ok := i.val.([]byte)
+	return v, ok
+}
+
+func (i *bareItem) BoolValue() (bool, bool) {
+	v, ok := i.val.(bool)
+	return v, ok
+}
+
+func (i *bareItem) IntValue() (int64, bool) {
+	v, ok := i.val.(int64)
+	return v, ok
+}
+
+func (i *bareItem) FloatValue() (float64, bool) {
+	v, ok := i.val.(float64)
+	return v, ok
+}
+
+func (i *bareItem) TokenValue() (Token, bool) {
+	v, ok := i.val.(Token)
+	return v, ok
+}
+
+func (i *bareItem) DecimalValue() (Decimal, bool) {
+	v, ok := i.val.(Decimal)
+	return v, ok
+}
+
+func (i *bareItem) DateValue() (time.Time, bool) {
+	v, ok := i.val.(time.Time)
+	return v, ok
+}
+
+func (i *bareItem) DisplayStringValue() (DisplayString, bool) {
+	v, ok := i.val.(DisplayString)
+	return v, ok
+}
+
+// String returns the serialized form, or "<invalid>" if the value
+// cannot be serialized.
+func (i *bareItem) String() string {
+	return serializeString(BareItem(i))
+}
+
+// bareItemValue returns the value of bi, whose type is the return
+// type of the As* method for bi.Type().
+func bareItemValue(bi BareItem) interface{} {
+	switch bi.Type() {
+	case ItemTypeString:
+		return bi.AsString()
+	case ItemTypeByteSeq:
+		return bi.AsByteSeq()
+	case ItemTypeBool:
+		return bi.AsBool()
+	case ItemTypeInt:
+		return bi.AsInt()
+	case ItemTypeFloat:
+		return bi.AsFloat()
+	case ItemTypeToken:
+		return bi.AsToken()
+	case ItemTypeDecimal:
+		return bi.AsDecimal()
+	case ItemTypeDate:
+		return bi.AsDate()
+	case ItemTypeDisplayString:
+		return bi.AsDisplayString()
+	default:
+		return nil
+	}
+}
+
 type item struct {
 	bareItem BareItem
 	params   Parameters
+	raw      []byte
 }
 
 // NewItem creates a new Item.
@@ -229,6 +565,20 @@ func (i *item) Parameters() Parameters {
 	return i.params
 }
 
+func (i *item) Param(name string) (BareItem, bool) {
+	return loadParam(i.params, name)
+}
+
+func (i *item) RawBytes() []byte {
+	return i.raw
+}
+
+// String returns the serialized form, or "<invalid>" if the value
+// cannot be serialized.
+func (i *item) String() string {
+	return serializeString(Item(i))
+}
+
 type innerList struct {
 	items  []Item
 	params Parameters
@@ -250,6 +600,25 @@ func (l *innerList) Parameters() Parameters {
 	return l.params
 }
 
+func (l *innerList) Param(name string) (BareItem, bool) {
+	return loadParam(l.params, name)
+}
+
+func (l *innerList) WithItem(item Item) InnerList {
+	items := make([]Item, 0, len(l.items)+1)
+	items = append(items, l.items...)
+	return &innerList{
+		items:  append(items, item),
+		params: l.params,
+	}
+}
+
+// String returns the serialized form, or "<invalid>" if the value
+// cannot be serialized.
+func (l *innerList) String() string {
+	return serializeString(InnerList(l))
+}
+
 type paramItem struct {
 	name  string
 	value BareItem
@@ -286,6 +655,13 @@ func (p *parameters) Load(name string) (value BareItem, ok bool) {
 	return p.items[i].value, true
 }
 
+func (p *parameters) LoadOrDefault(name string, def BareItem) BareItem {
+	if v, ok := p.Load(name); ok {
+		return v
+	}
+	return def
+}
+
 func (p *parameters) Range(f func(name string, value BareItem) bool) {
 	for _, it := range p.items {
 		if !f(it.name, it.value) {
@@ -294,7 +670,23 @@ func (p *parameters) Range(f func(name string, value BareItem) bool) {
 	}
 }
 
+func (p *parameters) RangeSorted(f func(name string, value BareItem) bool) {
+	items := make([]paramItem, len(p.items))
+	copy(items, p.items)
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].name < items[j].name
+	})
+	for _, it := range items {
+		if !f(it.name, it.value) {
+			return
+		}
+	}
+}
+
 func (p *parameters) Store(name string, value BareItem) {
+	if value == nil {
+		value = &bareItem{val: true}
+	}
 	i := p.index(name)
 	if i == -1 {
 		p.items = append(p.items, paramItem{name: name, value: value})
@@ -303,10 +695,56 @@ func (p *parameters) Store(name string, value BareItem) {
 	p.items[i].value = value
 }
 
+func (p *parameters) StoreAll(entries []Parameter) {
+	for _, e := range entries {
+		p.Store(e.Name, e.Value)
+	}
+}
+
 func (p *parameters) Len() int {
 	return len(p.items)
 }
 
+func (p *parameters) Keys() []string {
+	keys := make([]string, len(p.items))
+	for i, it := range p.items {
+		keys[i] = it.name
+	}
+	return keys
+}
+
+func (p *parameters) Values() []BareItem {
+	values := make([]BareItem, len(p.items))
+	for i, it := range p.items {
+		values[i] = it.value
+	}
+	return values
+}
+
+func (p *parameters) Clone() Parameters {
+	return CloneParameters(p)
+}
+
+func (p *parameters) Merge(other Parameters) Parameters {
+	ret := CloneParameters(p).(*parameters)
+	if other == nil {
+		return ret
+	}
+	other.Range(func(name string, value BareItem) bool {
+		ret.Store(name, CloneBareItem(value))
+		return true
+	})
+	return ret
+}
+
+// loadParam is like params.Load but returns false for nil params.
+func loadParam(params Parameters, name string) (BareItem, bool) {
+	if params == nil {
+		return nil, false
+	}
+	return params.Load(name)
+}
+
 func (p *parameters) index(name string) int {
 	for i, it := range p.items {
 		if it.name == name {
@@ -318,26 +756,47 @@ func (p *parameters) index(name string) int {
 
 type member struct {
 	val interface{}
+	raw []byte
 }
 
 // NewMember creates a new member.
 // It panics if value type is not one of the return value type
 // of Member As* methods.
 func NewMember(val interface{}) Member {
-	m := &member{val: val}
-	// Do type check
-	m.Type()
+	m, err := NewMemberChecked(val)
+	if err != nil {
+		panic(err.Error())
+	}
 	return m
 }
 
+// NewMemberChecked is like NewMember but returns an error instead of
+// panicking if value type is not supported.
+func NewMemberChecked(val interface{}) (Member, error) {
+	if memberType(val) == MemberTypeInvalid {
+		return nil, fmt.Errorf("invalid member type: %T", val)
+	}
+	return &member{val: val}, nil
+}
+
 func (m *member) Type() MemberType {
-	switch m.val.(type) {
+	t := memberType(m.val)
+	if t == MemberTypeInvalid {
+		panic("invalid Member type")
+	}
+	return t
+}
+
+// memberType returns the member type for val, or MemberTypeInvalid
+// if val is not a valid value for Member.
+func memberType(val interface{}) MemberType {
+	switch val.(type) {
 	case Item:
 		return MemberTypeItem
 	case InnerList:
 		return MemberTypeInnerList
 	default:
-		panic("invalid Member type")
+		return MemberTypeInvalid
 	}
 }
 
@@ -349,6 +808,66 @@ func (m *member) AsInnerList() InnerList {
 	return m.val.(InnerList)
 }
 
+func (m *member) RawBytes() []byte {
+	return m.raw
+}
+
+// String returns the serialized form, or "<invalid>" if the value
+// cannot be serialized.
+func (m *member) String() string {
+	return serializeString(Member(m))
+}
+
+// String returns the serialized form, or "<invalid>" if the value
+// cannot be serialized.
+func (l List) String() string {
+	return serializeString(l)
+}
+
+// Tokens returns the "Token" values of the members of l. Parameters
+// of the members are ignored. It returns an error if a member is not
+// an Item whose bare item is a "Token".
+func (l List) Tokens() ([]Token, error) {
+	tokens := make([]Token, len(l))
+	for i, m := range l {
+		if m == nil || m.Type() != MemberTypeItem {
+			return nil, fmt.Errorf("member %d is not an item", i)
+		}
+		it := m.AsItem()
+		if it == nil || it.BareItem() == nil {
+			return nil, fmt.Errorf("member %d is not a token", i)
+		}
+		tok, ok := it.BareItem().TokenValue()
+		if !ok {
+			return nil, fmt.Errorf("member %d is not a token but %s", i, it.BareItem().Type())
+		}
+		tokens[i] = tok
+	}
+	return tokens, nil
+}
+
+// ToDictionary returns a new Dictionary which has the members of l
+// keyed by keyFunc, in the order of l. Members for which keyFunc
+// returns false are skipped. It returns an error if keyFunc returns
+// an invalid key or the same key for two members.
+func (l List) ToDictionary(keyFunc func(Member) (string, bool)) (Dictionary, error) {
+	d := &dictionary{items: make([]dictItem, 0, len(l))}
+	for _, m := range l {
+		key, ok := keyFunc(m)
+		if !ok {
+			continue
+		}
+		if !ValidKey(key) {
+			return nil, fmt.Errorf("invalid key: %q", key)
+		}
+		if d.index(key) != -1 {
+			return nil, fmt.Errorf("duplicate key: %q", key)
+		}
+		d.items = append(d.items, dictItem{name: key, value: m})
+	}
+	return d, nil
+}
+
 type dictItem struct {
 	name  string
 	value Member
@@ -385,6 +904,22 @@ func (d *dictionary) Load(name string) (value Member, ok bool) {
 	return d.items[i].value, true
 }
 
+func (d *dictionary) LoadOrDefault(name string, def Member) Member {
+	if v, ok := d.Load(name); ok && v != nil {
+		return v
+	}
+	return def
+}
+
+func (d *dictionary) LoadFold(name string) (value Member, ok bool) {
+	for _, it := range d.items {
+		if strings.EqualFold(it.name, name) {
+			return it.value, true
+		}
+	}
+	return nil, false
+}
+
 func (d *dictionary) Range(f func(name string, value Member) bool) {
 	for _, it := range d.items {
 		if !f(it.name, it.value) {
@@ -402,10 +937,84 @@ func (d *dictionary) Store(name string, value Member) {
 	d.items[i].value = value
 }
 
+func (d *dictionary) StoreAll(entries []DictionaryEntry) {
+	for _, e := range entries {
+		d.Store(e.Name, e.Value)
+	}
+}
+
+func (d *dictionary) Rename(oldName, newName string) error {
+	i := d.index(oldName)
+	if i == -1 {
+		return fmt.Errorf("key not found: %q", oldName)
+	}
+	if oldName == newName {
+		return nil
+	}
+	if d.index(newName) != -1 {
+		return fmt.Errorf("duplicate key: %q", newName)
+	}
+	d.items[i].name = newName
+	return nil
+}
+
+func (d *dictionary) InsertAt(index int, name string, value Member) error {
+	if index < 0 || index > len(d.items) {
+		return fmt.Errorf("index out of range: %d", index)
+	}
+	if d.index(name) != -1 {
+		return fmt.Errorf("duplicate key: %q", name)
+	}
+	d.items = append(d.items, dictItem{})
+	copy(d.items[index+1:], d.items[index:])
+	d.items[index] = dictItem{name: name, value: value}
+	return nil
+}
+
+func (d *dictionary) MoveTo(name string, index int) error {
+	i := d.index(name)
+	if i == -1 {
+		return fmt.Errorf("key not found: %q", name)
+	}
+	if index < 0 || index >= len(d.items) {
+		return fmt.Errorf("index out of range: %d", index)
+	}
+	it := d.items[i]
+	if i < index {
+		copy(d.items[i:], d.items[i+1:index+1])
+	} else {
+		copy(d.items[index+1:], d.items[index:i])
+	}
+	d.items[index] = it
+	return nil
+}
+
 func (d *dictionary) Len() int {
 	return len(d.items)
 }
 
+func (d *dictionary) Keys() []string {
+	keys := make([]string, len(d.items))
+	for i, it := range d.items {
+		keys[i] = it.name
+	}
+	return keys
+}
+
+func (d *dictionary) Members() []Member {
+	members := make([]Member, len(d.items))
+	for i, it := range d.items {
+		members[i] = it.value
+	}
+	return members
+}
+
+// String returns the serialized form, or "<invalid>" if the value
+// cannot be serialized.
+func (d *dictionary) String() string {
+	return serializeString(Dictionary(d))
+}
+
 func (d *dictionary) index(name string) int {
 	for i, it := range d.items {
 		if it.name == name {
@@ -415,7 +1024,8 @@ func (d *dictionary) index(name string) int {
 	return -1
 }
 
-// String returns the string representation for ItemType
+// String returns the string representation for ItemType.
+// It returns "ItemType(n)" for ItemTypeInvalid and unknown values.
 func (t ItemType) String() string {
 	switch t {
 	case ItemTypeString:
@@ -430,12 +1040,19 @@ func (t ItemType) String() string {
 		return "float"
 	case ItemTypeToken:
 		return "token"
+	case ItemTypeDecimal:
+		return "decimal"
+	case ItemTypeDate:
+		return "date"
+	case ItemTypeDisplayString:
+		return "displayString"
 	default:
-		panic("invalidItemType")
+		return "ItemType(" + strconv.Itoa(int(t)) + ")"
 	}
 }
 
-// String returns the string representation for MemberType
+// String returns the string representation for MemberType.
+// It returns "MemberType(n)" for MemberTypeInvalid and unknown values.
 func (t MemberType) String() string {
 	switch t {
 	case MemberTypeItem:
@@ -443,6 +1060,28 @@ func (t MemberType) String() string {
 	case MemberTypeInnerList:
 		return "innerList"
 	default:
-		panic("invalidMemberType")
+		return "MemberType(" + strconv.Itoa(int(t)) + ")"
+	}
+}
+
+// ParseItemType returns the ItemType whose String method returns s.
+// It returns an error if s is not a name of a valid ItemType.
+func ParseItemType(s string) (ItemType, error) {
+	for t := ItemTypeString; t <= ItemTypeDisplayString; t++ {
+		if t.String() == s {
+			return t, nil
+		}
+	}
+	return ItemTypeInvalid, fmt.Errorf("unknown item type: %q", s)
+}
+
+// ParseMemberType returns the MemberType whose String method returns s.
+// It returns an error if s is not a name of a valid MemberType.
+func ParseMemberType(s string) (MemberType, error) {
+	for t := MemberTypeItem; t <= MemberTypeInnerList; t++ {
+		if t.String() == s {
+			return t, nil
+		}
 	}
+	return MemberTypeInvalid, fmt.Errorf("unknown member type: %q", s)
 }
@@ -0,0 +1,657 @@
+package stheader_test
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestBareItemIsInteger(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  bool
+	}{
+		{input: "5", want: true},
+		{input: "-5", want: true},
+		{input: "5.0", want: false},
+		{input: "?1", want: false},
+		{input: `"5"`, want: false},
+	}
+	for _, c := range testCases {
+		item, err := stheader.NewParser(c.input).ParseItem()
+		if err != nil {
+			t.Fatalf("parse %q: %s", c.input, err)
+		}
+		if got := item.BareItem().IsInteger(); got != c.want {
+			t.Errorf("IsInteger mismatch for %q, got=%v, want=%v", c.input, got, c.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	input := `a=(1 2);x, b=*aGVsbG8=*, c="foo";y=?0`
+	dict, err := stheader.NewParser(input).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprintf("%v", dict), "a=(1 2);x, b=*aGVsbG8=*, c=\"foo\";y=?0"; got != want {
+		t.Errorf("dictionary mismatch, got=%q, want=%q", got, want)
+	}
+
+	a, _ := dict.Load("a")
+	if got, want := fmt.Sprint(a), "(1 2);x"; got != want {
+		t.Errorf("member mismatch, got=%q, want=%q", got, want)
+	}
+	if got, want := fmt.Sprint(a.AsInnerList()), "(1 2);x"; got != want {
+		t.Errorf("inner list mismatch, got=%q, want=%q", got, want)
+	}
+	c, _ := dict.Load("c")
+	if got, want := fmt.Sprint(c.AsItem()), `"foo";y=?0`; got != want {
+		t.Errorf("item mismatch, got=%q, want=%q", got, want)
+	}
+	if got, want := fmt.Sprint(c.AsItem().BareItem()), `"foo"`; got != want {
+		t.Errorf("bare item mismatch, got=%q, want=%q", got, want)
+	}
+
+	list := stheader.List{a, c}
+	if got, want := fmt.Sprint(list), `(1 2);x, "foo";y=?0`; got != want {
+		t.Errorf("list mismatch, got=%q, want=%q", got, want)
+	}
+}
+
+func TestStringInvalid(t *testing.T) {
+	testCases := []fmt.Stringer{
+		stheader.NewItem(nil, nil).(fmt.Stringer),
+		stheader.NewItem(stheader.NewBareItem(stheader.Token("1a")), nil).(fmt.Stringer),
+		stheader.NewInnerList([]stheader.Item{nil}, nil).(fmt.Stringer),
+		stheader.List{nil},
+	}
+	for i, s := range testCases {
+		if got, want := s.String(), "<invalid>"; got != want {
+			t.Errorf("result mismatch for case %d, got=%q, want=%q", i, got, want)
+		}
+	}
+}
+
+func TestBareItemValue(t *testing.T) {
+	values := []interface{}{
+		"foo",
+		[]byte("foo"),
+		true,
+		int64(1),
+		1.5,
+		stheader.Token("foo"),
+		stheader.NewDecimal(1500),
+		time.Unix(1, 0),
+		stheader.DisplayString("foo"),
+	}
+	for _, v := range values {
+		bi := stheader.NewBareItem(v)
+		typ := bi.Type()
+
+		s, ok := bi.StringValue()
+		checkValue(t, bi, typ == stheader.ItemTypeString, ok, s, v)
+		b, ok := bi.ByteSeqValue()
+		checkValue(t, bi, typ == stheader.ItemTypeByteSeq, ok, b, v)
+		bv, ok := bi.BoolValue()
+		checkValue(t, bi, typ == stheader.ItemTypeBool, ok, bv, v)
+		i, ok := bi.IntValue()
+		checkValue(t, bi, typ == stheader.ItemTypeInt, ok, i, v)
+		f, ok := bi.FloatValue()
+		checkValue(t, bi, typ == stheader.ItemTypeFloat, ok, f, v)
+		tok, ok := bi.TokenValue()
+		checkValue(t, bi, typ == stheader.ItemTypeToken, ok, tok, v)
+		d, ok := bi.DecimalValue()
+		checkValue(t, bi, typ == stheader.ItemTypeDecimal, ok, d, v)
+		date, ok := bi.DateValue()
+		checkValue(t, bi, typ == stheader.ItemTypeDate, ok, date, v)
+		ds, ok := bi.DisplayStringValue()
+		checkValue(t, bi, typ == stheader.ItemTypeDisplayString, ok, ds, v)
+	}
+}
+
+func checkValue(t *testing.T, bi stheader.BareItem, wantOK, gotOK bool, got, want interface{}) {
+	t.Helper()
+	if gotOK != wantOK {
+		t.Errorf("ok mismatch for %s, got=%v, want=%v", bi.Type(), gotOK, wantOK)
+		return
+	}
+	if gotOK && !reflect.DeepEqual(got, want) {
+		t.Errorf("value mismatch for %s, got=%v, want=%v", bi.Type(), got, want)
+	}
+	if !gotOK && !reflect.ValueOf(got).IsZero() {
+		t.Errorf("value should be zero for mismatched type %s, got=%v", bi.Type(), got)
+	}
+}
+
+func TestNewBareItemConversion(t *testing.T) {
+	testCases := []struct {
+		val  interface{}
+		want interface{}
+	}{
+		{val: 1, want: int64(1)},
+		{val: int32(-2), want: int64(-2)},
+		{val: uint(3), want: int64(3)},
+		{val: uint(math.MaxInt64), want: int64(math.MaxInt64)},
+		{val: float32(1.5), want: 1.5},
+	}
+	for _, c := range testCases {
+		bi := stheader.NewBareItem(c.val)
+		var got interface{}
+		switch bi.Type() {
+		case stheader.ItemTypeInt:
+			got = bi.AsInt()
+		case stheader.ItemTypeFloat:
+			got = bi.AsFloat()
+		}
+		if got != c.want {
+			t.Errorf("value mismatch for %T(%v), got=%#v, want=%#v", c.val, c.val, got, c.want)
+		}
+	}
+
+	for _, val := range []interface{}{uint(math.MaxInt64) + 1, int8(1), struct{}{}, nil} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("should panic for %T(%v)", val, val)
+				}
+			}()
+			stheader.NewBareItem(val)
+		}()
+	}
+}
+
+func TestNewChecked(t *testing.T) {
+	for _, val := range []interface{}{uint(math.MaxInt64) + 1, int8(1), struct{}{}, nil} {
+		bi, err := stheader.NewBareItemChecked(val)
+		if err == nil || bi != nil {
+			t.Errorf("NewBareItemChecked should fail for %T(%v)", val, val)
+		}
+	}
+	if _, err := stheader.NewBareItemChecked(1); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	for _, val := range []interface{}{stheader.NewBareItem(1), "foo", nil} {
+		m, err := stheader.NewMemberChecked(val)
+		if err == nil || m != nil {
+			t.Errorf("NewMemberChecked should fail for %T(%v)", val, val)
+		}
+	}
+	item := stheader.NewItem(stheader.NewBareItem(1), nil)
+	if m, err := stheader.NewMemberChecked(item); err != nil || m.Type() != stheader.MemberTypeItem {
+		t.Errorf("unexpected result for item, m=%v, err=%v", m, err)
+	}
+	list := stheader.NewInnerList([]stheader.Item{item}, nil)
+	if m, err := stheader.NewMemberChecked(list); err != nil || m.Type() != stheader.MemberTypeInnerList {
+		t.Errorf("unexpected result for inner list, m=%v, err=%v", m, err)
+	}
+}
+
+func TestParametersRangeSorted(t *testing.T) {
+	item, err := stheader.NewParser(`1;c=3;a=1;b=2`).ParseItem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	item.Parameters().RangeSorted(func(name string, value stheader.BareItem) bool {
+		names = append(names, fmt.Sprintf("%s=%v", name, value))
+		return true
+	})
+	if want := []string{"a=1", "b=2", "c=3"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("result mismatch, got=%q, want=%q", names, want)
+	}
+
+	names = nil
+	item.Parameters().RangeSorted(func(name string, value stheader.BareItem) bool {
+		names = append(names, name)
+		return false
+	})
+	if want := []string{"a"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("result mismatch after stop, got=%q, want=%q", names, want)
+	}
+
+	got, err := stheader.Serialize(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1;c=3;a=1;b=2"; got != want {
+		t.Errorf("serialize mismatch, got=%q, want=%q", got, want)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	const input = `b=1;y=2;x=3, a=(4 5), c=6`
+	dict, err := stheader.NewParser(input).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := dict.Keys(), []string{"b", "a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keys mismatch, got=%q, want=%q", got, want)
+	}
+	var members []string
+	for _, m := range dict.Members() {
+		members = append(members, fmt.Sprint(m))
+	}
+	if want := []string{"1;y=2;x=3", "(4 5)", "6"}; !reflect.DeepEqual(members, want) {
+		t.Errorf("members mismatch, got=%q, want=%q", members, want)
+	}
+
+	params := dict.Members()[0].AsItem().Parameters()
+	if got, want := params.Keys(), []string{"y", "x"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parameter keys mismatch, got=%q, want=%q", got, want)
+	}
+	var values []int64
+	for _, v := range params.Values() {
+		values = append(values, v.AsInt())
+	}
+	if want := []int64{2, 3}; !reflect.DeepEqual(values, want) {
+		t.Errorf("parameter values mismatch, got=%v, want=%v", values, want)
+	}
+
+	if got, err := stheader.Serialize(dict); err != nil {
+		t.Fatal(err)
+	} else if got != input {
+		t.Errorf("serialize mismatch, got=%q, want=%q", got, input)
+	}
+
+	if got := stheader.NewDictionary().Keys(); len(got) != 0 {
+		t.Errorf("keys of empty dictionary should be empty, got=%q", got)
+	}
+}
+
+func TestDictionaryLoadFold(t *testing.T) {
+	dict, err := stheader.NewParser(`foo=1, bar=2`).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := dict.LoadFold("BAR")
+	if !ok {
+		t.Fatal("should find BAR")
+	}
+	if got, want := m.AsItem().BareItem().AsInt(), int64(2); got != want {
+		t.Errorf("value mismatch, got=%d, want=%d", got, want)
+	}
+	if _, ok := dict.LoadFold("Baz"); ok {
+		t.Error("should not find Baz")
+	}
+	if _, ok := dict.Load("BAR"); ok {
+		t.Error("Load should be case-sensitive")
+	}
+}
+
+func TestInnerListWithItem(t *testing.T) {
+	list, err := stheader.NewParser(`(1 2);x, 3`).ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := list[0].AsInnerList()
+	got := orig.WithItem(stheader.NewItem(stheader.NewBareItem(stheader.Token("t")), nil))
+	if want := "(1 2 t);x"; fmt.Sprint(got) != want {
+		t.Errorf("result mismatch, got=%q, want=%q", fmt.Sprint(got), want)
+	}
+	if want := "(1 2);x"; fmt.Sprint(orig) != want {
+		t.Errorf("original modified, got=%q, want=%q", fmt.Sprint(orig), want)
+	}
+
+	other, err := stheader.NewParser(`"a", (b)`).ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined := stheader.AppendList(list[:1], other)
+	if want := `(1 2);x, "a", (b)`; fmt.Sprint(joined) != want {
+		t.Errorf("appended list mismatch, got=%q, want=%q", fmt.Sprint(joined), want)
+	}
+	if want := `(1 2);x, 3`; fmt.Sprint(list) != want {
+		t.Errorf("first list modified, got=%q, want=%q", fmt.Sprint(list), want)
+	}
+	if want := `"a", (b)`; fmt.Sprint(other) != want {
+		t.Errorf("second list modified, got=%q, want=%q", fmt.Sprint(other), want)
+	}
+}
+
+func TestParametersMerge(t *testing.T) {
+	testCases := []struct {
+		base, other string
+		want        string
+	}{
+		{base: `a;x=1;y=2`, other: `a;y=3;z=4`, want: `a;x=1;y=3;z=4`},
+		{base: `a;x=1`, other: `a;z=4;w`, want: `a;x=1;z=4;w`},
+		{base: `a;x=1;y=2`, other: `a;y="s";x=?0`, want: `a;x=?0;y="s"`},
+		{base: `a`, other: `a;z=4`, want: `a;z=4`},
+	}
+	for _, c := range testCases {
+		base, err := stheader.NewParser(c.base).ParseItem()
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, err := stheader.NewParser(c.other).ParseItem()
+		if err != nil {
+			t.Fatal(err)
+		}
+		params := base.Parameters()
+		if params == nil {
+			params = stheader.NewParameters()
+		}
+		merged := params.Merge(other.Parameters())
+		got := fmt.Sprint(stheader.NewItem(base.BareItem(), merged))
+		if got != c.want {
+			t.Errorf("result mismatch for %q and %q, got=%q, want=%q", c.base, c.other, got, c.want)
+		}
+		if fmt.Sprint(base) != c.base {
+			t.Errorf("base modified, got=%q, want=%q", fmt.Sprint(base), c.base)
+		}
+		if fmt.Sprint(other) != c.other {
+			t.Errorf("other modified, got=%q, want=%q", fmt.Sprint(other), c.other)
+		}
+	}
+}
+
+func TestParseItemType(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  stheader.ItemType
+	}{
+		{input: "string", want: stheader.ItemTypeString},
+		{input: "byteSeq", want: stheader.ItemTypeByteSeq},
+		{input: "bool", want: stheader.ItemTypeBool},
+		{input: "int", want: stheader.ItemTypeInt},
+		{input: "float", want: stheader.ItemTypeFloat},
+		{input: "token", want: stheader.ItemTypeToken},
+		{input: "decimal", want: stheader.ItemTypeDecimal},
+		{input: "date", want: stheader.ItemTypeDate},
+		{input: "displayString", want: stheader.ItemTypeDisplayString},
+	}
+	for _, c := range testCases {
+		got, err := stheader.ParseItemType(c.input)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", c.input, err)
+		} else if got != c.want {
+			t.Errorf("result mismatch for %q, got=%v, want=%v", c.input, got, c.want)
+		}
+	}
+	for _, input := range []string{"", "Int", "item"} {
+		if _, err := stheader.ParseItemType(input); err == nil {
+			t.Errorf("should fail for %q", input)
+		}
+	}
+}
+
+func TestParseMemberType(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  stheader.MemberType
+	}{
+		{input: "item", want: stheader.MemberTypeItem},
+		{input: "innerList", want: stheader.MemberTypeInnerList},
+	}
+	for _, c := range testCases {
+		got, err := stheader.ParseMemberType(c.input)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", c.input, err)
+		} else if got != c.want {
+			t.Errorf("result mismatch for %q, got=%v, want=%v", c.input, got, c.want)
+		}
+	}
+	for _, input := range []string{"", "list", "int"} {
+		if _, err := stheader.ParseMemberType(input); err == nil {
+			t.Errorf("should fail for %q", input)
+		}
+	}
+}
+
+func TestTypeStringInvalid(t *testing.T) {
+	testCases := []struct {
+		typ  fmt.Stringer
+		want string
+	}{
+		{typ: stheader.ItemTypeInvalid, want: "ItemType(0)"},
+		{typ: stheader.ItemType(1000), want: "ItemType(1000)"},
+		{typ: stheader.ItemType(-1), want: "ItemType(-1)"},
+		{typ: stheader.MemberTypeInvalid, want: "MemberType(0)"},
+		{typ: stheader.MemberType(1000), want: "MemberType(1000)"},
+	}
+	for _, c := range testCases {
+		if got := c.typ.String(); got != c.want {
+			t.Errorf("result mismatch, got=%q, want=%q", got, c.want)
+		}
+	}
+}
+
+func TestListToDictionary(t *testing.T) {
+	firstToken := func(m stheader.Member) (string, bool) {
+		var bi stheader.BareItem
+		switch m.Type() {
+		case stheader.MemberTypeItem:
+			bi = m.AsItem().BareItem()
+		case stheader.MemberTypeInnerList:
+			items := m.AsInnerList().Items()
+			if len(items) == 0 {
+				return "", false
+			}
+			bi = items[0].BareItem()
+		}
+		tok, ok := bi.TokenValue()
+		return string(tok), ok
+	}
+
+	list, err := stheader.NewParser(`gzip;q=1, (br 2), "skipped", (), deflate`).ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict, err := list.ToDictionary(firstToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(dict), `gzip=gzip;q=1, br=(br 2), deflate=deflate`; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+
+	list, err = stheader.NewParser(`gzip, (gzip 2)`).ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := list.ToDictionary(firstToken); err == nil {
+		t.Error("should fail for a duplicate key")
+	}
+
+	list, err = stheader.NewParser(`Gzip`).ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := list.ToDictionary(firstToken); err == nil {
+		t.Error("should fail for an invalid key")
+	}
+}
+
+func TestParam(t *testing.T) {
+	list, err := stheader.NewParser(`a;x=1, b, (c);y="s", (d)`).ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := list[0].AsItem().Param("x"); !ok || v.AsInt() != 1 {
+		t.Errorf("item param mismatch, got=%v, %v", v, ok)
+	}
+	if _, ok := list[0].AsItem().Param("y"); ok {
+		t.Error("item should not have param y")
+	}
+	if _, ok := list[1].AsItem().Param("x"); ok {
+		t.Error("item without parameters should not have param x")
+	}
+	if _, ok := stheader.NewItem(stheader.NewBareItem(int64(1)), nil).Param("x"); ok {
+		t.Error("item with nil parameters should not have param x")
+	}
+	if v, ok := list[2].AsInnerList().Param("y"); !ok || v.AsString() != "s" {
+		t.Errorf("inner list param mismatch, got=%v, %v", v, ok)
+	}
+	if _, ok := list[3].AsInnerList().Param("y"); ok {
+		t.Error("inner list without parameters should not have param y")
+	}
+	if _, ok := stheader.NewInnerList(nil, nil).Param("y"); ok {
+		t.Error("inner list with nil parameters should not have param y")
+	}
+}
+
+func TestListTokens(t *testing.T) {
+	list, err := stheader.NewParser(`accept-encoding, user-agent;x=1, foo`).ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := list.Tokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []stheader.Token{"accept-encoding", "user-agent", "foo"}; !reflect.DeepEqual(tokens, want) {
+		t.Errorf("result mismatch, got=%q, want=%q", tokens, want)
+	}
+
+	for _, input := range []string{`a, "b"`, `a, (b c)`, `a, 1`} {
+		list, err := stheader.NewParser(input).ParseList()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := list.Tokens(); err == nil {
+			t.Errorf("should fail for %q", input)
+		}
+	}
+}
+
+func TestLoadOrDefault(t *testing.T) {
+	item, err := stheader.NewParser(`a;q=5`).ParseItem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := item.Parameters()
+	params.Store("n", nil)
+	def := stheader.NewBareItem(int64(1))
+	if got := params.LoadOrDefault("q", def).AsInt(); got != 5 {
+		t.Errorf("value mismatch for stored parameter, got=%d, want=5", got)
+	}
+	if got := params.LoadOrDefault("x", def); got != def {
+		t.Errorf("should return default for missing parameter, got=%v", got)
+	}
+	if got := params.LoadOrDefault("n", def); !got.AsBool() {
+		t.Errorf("nil parameter should be stored as true, got=%v", got)
+	}
+
+	dict, err := stheader.NewParser(`a=2`).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict.Store("n", nil)
+	defMember := stheader.NewMember(stheader.NewItem(def, nil))
+	if got := dict.LoadOrDefault("a", defMember).AsItem().BareItem().AsInt(); got != 2 {
+		t.Errorf("value mismatch for stored member, got=%d, want=2", got)
+	}
+	if got := dict.LoadOrDefault("x", defMember); got != defMember {
+		t.Errorf("should return default for missing member, got=%v", got)
+	}
+	if got := dict.LoadOrDefault("n", defMember); got != defMember {
+		t.Errorf("should return default for nil member, got=%v", got)
+	}
+}
+
+func TestDictionaryRename(t *testing.T) {
+	dict, err := stheader.NewParser(`a=1, b=2, c=3`).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dict.Rename("b", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(dict), `a=1, x=2, c=3`; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+	if err := dict.Rename("x", "x"); err != nil {
+		t.Errorf("renaming to the same name should succeed, got=%v", err)
+	}
+	if err := dict.Rename("b", "y"); err == nil {
+		t.Error("should fail for a missing key")
+	}
+	if err := dict.Rename("a", "c"); err == nil {
+		t.Error("should fail for an existing new key")
+	}
+	if got, want := fmt.Sprint(dict), `a=1, x=2, c=3`; got != want {
+		t.Errorf("failed renames should not modify, got=%q, want=%q", got, want)
+	}
+}
+
+func TestDictionaryInsertAtAndMoveTo(t *testing.T) {
+	dict, err := stheader.NewParser(`a=1, b=2, c=3`).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	member := func(v int64) stheader.Member {
+		return stheader.NewMember(stheader.NewItem(stheader.NewBareItem(v), nil))
+	}
+	steps := []struct {
+		op      func() error
+		want    string
+		wantErr bool
+	}{
+		{op: func() error { return dict.InsertAt(0, "x", member(9)) }, want: `x=9, a=1, b=2, c=3`},
+		{op: func() error { return dict.InsertAt(2, "y", member(8)) }, want: `x=9, a=1, y=8, b=2, c=3`},
+		{op: func() error { return dict.InsertAt(5, "z", member(7)) }, want: `x=9, a=1, y=8, b=2, c=3, z=7`},
+		{op: func() error { return dict.InsertAt(7, "w", member(6)) }, wantErr: true},
+		{op: func() error { return dict.InsertAt(-1, "w", member(6)) }, wantErr: true},
+		{op: func() error { return dict.InsertAt(0, "a", member(6)) }, wantErr: true},
+		{op: func() error { return dict.MoveTo("x", 5) }, want: `a=1, y=8, b=2, c=3, z=7, x=9`},
+		{op: func() error { return dict.MoveTo("c", 0) }, want: `c=3, a=1, y=8, b=2, z=7, x=9`},
+		{op: func() error { return dict.MoveTo("y", 2) }, want: `c=3, a=1, y=8, b=2, z=7, x=9`},
+		{op: func() error { return dict.MoveTo("a", 3) }, want: `c=3, y=8, b=2, a=1, z=7, x=9`},
+		{op: func() error { return dict.MoveTo("a", 6) }, wantErr: true},
+		{op: func() error { return dict.MoveTo("none", 0) }, wantErr: true},
+	}
+	want := fmt.Sprint(dict)
+	for i, s := range steps {
+		err := s.op()
+		if s.wantErr {
+			if err == nil {
+				t.Errorf("step %d should fail", i)
+			}
+		} else {
+			if err != nil {
+				t.Fatalf("unexpected error for step %d: %s", i, err)
+			}
+			want = s.want
+		}
+		if got := fmt.Sprint(dict); got != want {
+			t.Errorf("result mismatch after step %d, got=%q, want=%q", i, got, want)
+		}
+	}
+}
+
+func TestStoreAll(t *testing.T) {
+	item, err := stheader.NewParser(`a;x=1;y=2`).ParseItem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	item.Parameters().StoreAll([]stheader.Parameter{
+		{Name: "z", Value: stheader.NewBareItem(int64(3))},
+		{Name: "x", Value: stheader.NewBareItem(int64(4))},
+		{Name: "z", Value: stheader.NewBareItem(int64(5))},
+	})
+	if got, want := fmt.Sprint(item), `a;x=4;y=2;z=5`; got != want {
+		t.Errorf("parameters mismatch, got=%q, want=%q", got, want)
+	}
+
+	member := func(v int64) stheader.Member {
+		return stheader.NewMember(stheader.NewItem(stheader.NewBareItem(v), nil))
+	}
+	dict, err := stheader.NewParser(`a=1, b=2`).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict.StoreAll([]stheader.DictionaryEntry{
+		{Name: "c", Value: member(3)},
+		{Name: "a", Value: member(4)},
+		{Name: "c", Value: member(5)},
+	})
+	if got, want := fmt.Sprint(dict), `a=4, b=2, c=5`; got != want {
+		t.Errorf("dictionary mismatch, got=%q, want=%q", got, want)
+	}
+}
@@ -0,0 +1,179 @@
+package stheader
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ValidationError is the error returned by Validate.
+type ValidationError struct {
+	// Path is the location of the invalid value in the same form as
+	// the path of WalkFunc. For an invalid key, it ends with the key.
+	Path []string
+
+	// Err is the error which the serializer would return.
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Path) == 0 {
+		return "stheader: invalid value: " + e.Err.Error()
+	}
+	return "stheader: invalid value at " + strings.Join(e.Path, ".") + ": " + e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks that value can be serialized by the zero value of
+// Serializer. See Serializer.Validate for details.
+func Validate(value interface{}) error {
+	var s Serializer
+	return s.Validate(value)
+}
+
+// Validate checks that value can be serialized by s without producing
+// the output. It runs the same checks as the serializer for every key
+// and bare item, and returns a *ValidationError for the first
+// violation in the serialized order.
+// value must be a Dictionary, List, Member, Item, InnerList,
+// Parameters or BareItem.
+func (s *Serializer) Validate(value interface{}) error {
+	v := validator{s: s}
+	var err error
+	switch val := value.(type) {
+	case Dictionary:
+		err = v.validateDictionary(val)
+	case List:
+		err = v.validateList(val)
+	case Member:
+		err = v.validateMember(val)
+	case Item:
+		err = v.validateItem(val)
+	case InnerList:
+		err = v.validateInnerList(val)
+	case Parameters:
+		err = v.validateParameters(val)
+	case BareItem:
+		err = v.validateBareItem(val)
+	default:
+		err = errors.New("invalid value type")
+	}
+	if err != nil {
+		return &ValidationError{Path: v.path, Err: err}
+	}
+	return nil
+}
+
+// validator holds the path of the value being validated, which is
+// left at the invalid value when an error is returned.
+type validator struct {
+	s    *Serializer
+	path []string
+	buf  []byte
+}
+
+func (v *validator) push(name string) {
+	v.path = append(v.path, name)
+}
+
+func (v *validator) pop() {
+	v.path = v.path[:len(v.path)-1]
+}
+
+func (v *validator) validateDictionary(dict Dictionary) error {
+	if dict == nil {
+		return nil
+	}
+	var err error
+	dict.Range(func(name string, value Member) bool {
+		v.push(name)
+		if !ValidKey(name) {
+			err = errors.New("invalid key")
+			return false
+		}
+		if err = v.validateMember(value); err != nil {
+			return false
+		}
+		v.pop()
+		return true
+	})
+	return err
+}
+
+func (v *validator) validateList(list List) error {
+	for i, m := range list {
+		v.push(strconv.Itoa(i))
+		if err := v.validateMember(m); err != nil {
+			return err
+		}
+		v.pop()
+	}
+	return nil
+}
+
+func (v *validator) validateMember(m Member) error {
+	if m == nil {
+		return errors.New("nil member")
+	}
+	switch m.Type() {
+	case MemberTypeItem:
+		return v.validateItem(m.AsItem())
+	case MemberTypeInnerList:
+		return v.validateInnerList(m.AsInnerList())
+	default:
+		return errors.New("invalid member type")
+	}
+}
+
+func (v *validator) validateInnerList(list InnerList) error {
+	if list == nil {
+		return errors.New("nil inner list")
+	}
+	for i, it := range list.Items() {
+		v.push(strconv.Itoa(i))
+		if err := v.validateItem(it); err != nil {
+			return err
+		}
+		v.pop()
+	}
+	return v.validateParameters(list.Parameters())
+}
+
+func (v *validator) validateItem(it Item) error {
+	if it == nil {
+		return errors.New("nil item")
+	}
+	if err := v.validateBareItem(it.BareItem()); err != nil {
+		return err
+	}
+	return v.validateParameters(it.Parameters())
+}
+
+func (v *validator) validateParameters(params Parameters) error {
+	if params == nil {
+		return nil
+	}
+	var err error
+	params.Range(func(name string, value BareItem) bool {
+		v.push(name)
+		if !ValidKey(name) {
+			err = errors.New("invalid key")
+			return false
+		}
+		if err = v.validateBareItem(value); err != nil {
+			return false
+		}
+		v.pop()
+		return true
+	})
+	return err
+}
+
+func (v *validator) validateBareItem(bi BareItem) error {
+	var err error
+	v.buf, err = v.s.appendBareItem(v.buf[:0], bi)
+	return err
+}
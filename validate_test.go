@@ -0,0 +1,60 @@
+package stheader_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestValidate(t *testing.T) {
+	dict, err := stheader.NewParser(`a=(1 b;x=2), c="s";y`).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stheader.Validate(dict); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	invalidToken := stheader.NewItem(stheader.NewBareItem(stheader.Token("1x")), nil)
+	hugeInt := stheader.NewBareItem(int64(1_000_000_000_000_000))
+
+	a, _ := dict.Load("a")
+	items := append([]stheader.Item{}, a.AsInnerList().Items()...)
+	items[1] = invalidToken
+	dict.Store("a", stheader.NewMember(stheader.NewInnerList(items, nil)))
+	assertValidationError(t, stheader.Validate(dict), []string{"a", "1"})
+
+	params := stheader.NewParameters()
+	params.Store("q", hugeInt)
+	list := stheader.List{
+		stheader.NewMember(stheader.NewItem(stheader.NewBareItem("ok"), nil)),
+		stheader.NewMember(stheader.NewItem(stheader.NewBareItem(int64(1)), params)),
+	}
+	assertValidationError(t, stheader.Validate(list), []string{"1", "q"})
+
+	s := stheader.Serializer{IntegerDigitLimit: 16}
+	if err := s.Validate(list); err != nil {
+		t.Errorf("unexpected error with IntegerDigitLimit: %s", err)
+	}
+
+	bad := stheader.NewDictionary()
+	bad.Store("Bad", stheader.NewMember(stheader.NewItem(stheader.NewBareItem(int64(1)), nil)))
+	assertValidationError(t, stheader.Validate(bad), []string{"Bad"})
+
+	assertValidationError(t, stheader.Validate(hugeInt), nil)
+}
+
+func assertValidationError(t *testing.T, err error, wantPath []string) {
+	t.Helper()
+	var verr *stheader.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("should fail with ValidationError, got=%v", err)
+	}
+	if len(verr.Path) != 0 || len(wantPath) != 0 {
+		if !reflect.DeepEqual(verr.Path, wantPath) {
+			t.Errorf("path mismatch, got=%q, want=%q (%v)", verr.Path, wantPath, err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package stheader
+
+import (
+	"errors"
+	"strconv"
+)
+
+// WalkFunc is the type of the function called by Walk for each
+// BareItem. If it returns an error, Walk stops and returns the error.
+//
+// path describes the location of bi. It consists of dictionary keys,
+// list and inner list indices, and parameter names. For example, for
+// the dictionary `a=(1 2;x=3)`, the path of 3 is ["a", "1", "x"].
+// The path slice is reused among calls, so fn must copy it to retain.
+type WalkFunc func(path []string, bi BareItem) error
+
+// Walk calls fn for each BareItem in value in the serialized order,
+// including the values of parameters.
+// value must be a Dictionary, List, Member, Item, InnerList,
+// Parameters or BareItem, otherwise Walk returns an error.
+func Walk(value interface{}, fn WalkFunc) error {
+	switch v := value.(type) {
+	case Dictionary:
+		return walkDictionary(nil, v, fn)
+	case List:
+		return walkList(nil, v, fn)
+	case Member:
+		return walkMember(nil, v, fn)
+	case Item:
+		return walkItem(nil, v, fn)
+	case InnerList:
+		return walkInnerList(nil, v, fn)
+	case Parameters:
+		return walkParameters(nil, v, fn)
+	case BareItem:
+		return fn(nil, v)
+	default:
+		return errors.New("invalid value type")
+	}
+}
+
+// CountItems returns the number of bare items in value, which is the
+// number of calls of the function given to Walk. That is, it counts the
+// values of items including items in inner lists, and the values of
+// parameters of items and inner lists. Parameters with boolean true
+// values, which are serialized as names only, are counted too.
+// Dictionary keys and parameter names are not counted by themselves.
+// If value is invalid, it returns the count of the bare items before
+// the invalid part.
+func CountItems(value interface{}) int {
+	var n int
+	_ = Walk(value, func(path []string, bi BareItem) error {
+		n++
+		return nil
+	})
+	return n
+}
+
+func walkDictionary(path []string, dict Dictionary, fn WalkFunc) error {
+	if dict == nil {
+		return nil
+	}
+	var err error
+	dict.Range(func(name string, value Member) bool {
+		err = walkMember(append(path, name), value, fn)
+		return err == nil
+	})
+	return err
+}
+
+func walkList(path []string, list List, fn WalkFunc) error {
+	for i, m := range list {
+		if err := walkMember(append(path, strconv.Itoa(i)), m, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkMember(path []string, m Member, fn WalkFunc) error {
+	if m == nil {
+		return errors.New("nil member")
+	}
+	switch m.Type() {
+	case MemberTypeItem:
+		return walkItem(path, m.AsItem(), fn)
+	case MemberTypeInnerList:
+		return walkInnerList(path, m.AsInnerList(), fn)
+	default:
+		return errors.New("invalid member type")
+	}
+}
+
+func walkInnerList(path []string, list InnerList, fn WalkFunc) error {
+	if list == nil {
+		return errors.New("invalid inner list")
+	}
+	for i, it := range list.Items() {
+		if err := walkItem(append(path, strconv.Itoa(i)), it, fn); err != nil {
+			return err
+		}
+	}
+	return walkParameters(path, list.Parameters(), fn)
+}
+
+func walkItem(path []string, it Item, fn WalkFunc) error {
+	if it == nil || it.BareItem() == nil {
+		return errors.New("invalid item")
+	}
+	if err := fn(path, it.BareItem()); err != nil {
+		return err
+	}
+	return walkParameters(path, it.Parameters(), fn)
+}
+
+func walkParameters(path []string, params Parameters, fn WalkFunc) error {
+	if params == nil {
+		return nil
+	}
+	var err error
+	params.Range(func(name string, value BareItem) bool {
+		err = fn(append(path, name), value)
+		return err == nil
+	})
+	return err
+}
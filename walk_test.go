@@ -0,0 +1,106 @@
+package stheader_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestWalk(t *testing.T) {
+	dict, err := stheader.NewParser(`a=(1 2;x=3);y=4, b=5;z="s", c=()`).ParseDictionary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	err = stheader.Walk(dict, func(path []string, bi stheader.BareItem) error {
+		got = append(got, fmt.Sprintf("%s:%v", strings.Join(path, "/"), bi))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"a/0:1",
+		"a/1:2",
+		"a/1/x:3",
+		"a/y:4",
+		"b:5",
+		`b/z:"s"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("result mismatch,\ngot= %q\nwant=%q", got, want)
+	}
+
+	list, err := stheader.NewParser(`1, (2 3)`).ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	err = stheader.Walk(list, func(path []string, bi stheader.BareItem) error {
+		got = append(got, strings.Join(path, "/"))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"0", "1/0", "1/1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("result mismatch,\ngot= %q\nwant=%q", got, want)
+	}
+}
+
+func TestWalkStop(t *testing.T) {
+	list, err := stheader.NewParser(`1, 2, 3`).ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	errStop := errors.New("stop")
+	count := 0
+	err = stheader.Walk(list, func(path []string, bi stheader.BareItem) error {
+		count++
+		if bi.AsInt() == 2 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("error mismatch, got=%v, want=%v", err, errStop)
+	}
+	if count != 2 {
+		t.Errorf("count mismatch, got=%d, want=%d", count, 2)
+	}
+
+	if err := stheader.Walk(1, func([]string, stheader.BareItem) error { return nil }); err == nil {
+		t.Error("should fail for an invalid value type")
+	}
+}
+
+func TestCountItems(t *testing.T) {
+	testCases := []struct {
+		input      string
+		headerType string
+		want       int
+	}{
+		{input: ``, headerType: "list", want: 0},
+		{input: `a`, headerType: "item", want: 1},
+		{input: `a;x=1;y`, headerType: "item", want: 3},
+		{input: `1, (2 3), ()`, headerType: "list", want: 3},
+		{input: `(1;p 2);q=3, 4`, headerType: "list", want: 5},
+		{input: `a=1, b, c=(x y;z=1);w`, headerType: "dictionary", want: 6},
+	}
+	for _, c := range testCases {
+		v, err := parse(c.headerType, c.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := stheader.CountItems(v); got != c.want {
+			t.Errorf("count mismatch for %q, got=%d, want=%d", c.input, got, c.want)
+		}
+	}
+	if got := stheader.CountItems("invalid"); got != 0 {
+		t.Errorf("count mismatch for invalid value, got=%d, want=0", got)
+	}
+}
@@ -0,0 +1,71 @@
+package stheader
+
+import (
+	"fmt"
+	"sort"
+)
+
+// WeightedMember is a member of a List with its weight given by
+// the "q" parameter.
+type WeightedMember struct {
+	Member Member
+	// Q is the weight in the range [0, 1]. It is 1 if the member
+	// has no "q" parameter.
+	Q float64
+}
+
+// ParseWeightedList parses input as a List whose members may have
+// a "q" parameter like `a;q=0.5, b`, and returns the members sorted
+// by descending weight. Members of the same weight keep their order
+// in input.
+//
+// The "q" parameter must be an "Integer" or a "Decimal" in the range
+// [0, 1], otherwise ParseWeightedList returns an error.
+func ParseWeightedList(input string) ([]WeightedMember, error) {
+	list, err := NewParser(input).ParseList()
+	if err != nil {
+		return nil, err
+	}
+	members := make([]WeightedMember, len(list))
+	for i, m := range list {
+		q, err := memberWeight(m)
+		if err != nil {
+			return nil, fmt.Errorf("member %d: %w", i, err)
+		}
+		members[i] = WeightedMember{Member: m, Q: q}
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].Q > members[j].Q
+	})
+	return members, nil
+}
+
+func memberWeight(m Member) (float64, error) {
+	var params Parameters
+	switch m.Type() {
+	case MemberTypeItem:
+		params = m.AsItem().Parameters()
+	case MemberTypeInnerList:
+		params = m.AsInnerList().Parameters()
+	}
+	if params == nil {
+		return 1, nil
+	}
+	bi, ok := params.Load("q")
+	if !ok {
+		return 1, nil
+	}
+	var q float64
+	switch v := bareItemValue(bi).(type) {
+	case int64:
+		q = float64(v)
+	case Decimal:
+		q = v.Float64()
+	default:
+		return 0, fmt.Errorf("q must be a number, got %s", bi.Type())
+	}
+	if q < 0 || q > 1 {
+		return 0, fmt.Errorf("q must be in the range [0, 1], got %s", bi)
+	}
+	return q, nil
+}
@@ -0,0 +1,50 @@
+package stheader_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"gihtub.com/hnakamur/stheader"
+)
+
+func TestParseWeightedList(t *testing.T) {
+	members, err := stheader.ParseWeightedList(`text/plain;q=0.5, text/html, (a b);q=0.8, application/json;q=0.5, image/png;q=0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	var gotQ []float64
+	for _, m := range members {
+		got = append(got, fmt.Sprint(m.Member))
+		gotQ = append(gotQ, m.Q)
+	}
+	want := []string{
+		"text/html",
+		"(a b);q=0.8",
+		"text/plain;q=0.5",
+		"application/json;q=0.5",
+		"image/png;q=0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("members mismatch,\ngot= %q\nwant=%q", got, want)
+	}
+	if wantQ := []float64{1, 0.8, 0.5, 0.5, 0}; !reflect.DeepEqual(gotQ, wantQ) {
+		t.Errorf("weights mismatch, got=%v, want=%v", gotQ, wantQ)
+	}
+}
+
+func TestParseWeightedListError(t *testing.T) {
+	for _, input := range []string{
+		`a;q=1.5`,
+		`a;q=-0.1`,
+		`a;q=2`,
+		`a;q="0.5"`,
+		`a;q`,
+		`a;q=`,
+	} {
+		if _, err := stheader.ParseWeightedList(input); err == nil {
+			t.Errorf("should fail for %q", input)
+		}
+	}
+}